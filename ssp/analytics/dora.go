@@ -0,0 +1,212 @@
+// Package analytics computes delivery metrics from the deployment history of the Platform Dashboard.
+//
+// The four DORA metrics are approximated from deployment records:
+//
+//   - deployment frequency: successful deployments per day,
+//   - lead time for changes: median time from a deployment being requested to it completing,
+//   - change failure rate: the share of attempted deployments that failed,
+//   - time to restore: median time from a failed deployment to the deployment restoring the environment.
+//
+// What counts as a failure and as a restore can be customised through Options.
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+// Options configures the time range and the failure and restore definitions of a report.
+//
+// IsFailure defaults to deployments in the Failed state. IsRestore is asked about each deployment following an
+// unrestored failure, and defaults to the next deployment that completed and is not a failure itself.
+type Options struct {
+	From      time.Time
+	To        time.Time
+	IsFailure func(d *ssp.Deployment) bool
+	IsRestore func(failure *ssp.Deployment, d *ssp.Deployment) bool
+}
+
+// Metrics are the DORA metrics of a set of deployments. Durations are zero when there was nothing to measure.
+type Metrics struct {
+	Deployments         int
+	Successful          int
+	Failures            int
+	Restores            int
+	DeploymentFrequency float64
+	LeadTime            time.Duration
+	ChangeFailureRate   float64
+	TimeToRestore       time.Duration
+
+	leadTimes    []time.Duration
+	restoreTimes []time.Duration
+}
+
+// Report holds the metrics of each environment, and their rollups per stack and overall.
+type Report struct {
+	From         time.Time
+	To           time.Time
+	Environments map[ssp.EnvironmentRef]*Metrics
+	Stacks       map[string]*Metrics
+	Total        *Metrics
+}
+
+// Fetch lists the deployments started within the time range on each of the environments and computes the report.
+func Fetch(c *ssp.Client, envs []ssp.EnvironmentRef, opts *Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	history := make(map[ssp.EnvironmentRef][]*ssp.Deployment, len(envs))
+	for _, env := range envs {
+		deployments, err := c.ListDeployments(env.Stack, env.Environment, &ssp.DeploymentFilter{
+			DateStartedFrom: opts.From,
+			DateStartedTo:   opts.To,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed listing deployments of %s: '%s'", env, err)
+		}
+		history[env] = deployments
+	}
+
+	return Compute(history, opts)
+}
+
+// Compute calculates the report from already fetched deployments. Deployments started outside of the time range
+// are ignored. Like Fetch, it requires a time range.
+func Compute(history map[ssp.EnvironmentRef][]*ssp.Deployment, opts *Options) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	isFailure := opts.IsFailure
+	if isFailure == nil {
+		isFailure = func(d *ssp.Deployment) bool {
+			return d.State == ssp.StateFailed
+		}
+	}
+	isRestore := opts.IsRestore
+	if isRestore == nil {
+		isRestore = func(failure *ssp.Deployment, d *ssp.Deployment) bool {
+			return d.State == ssp.StateCompleted && !isFailure(d)
+		}
+	}
+
+	r := &Report{
+		From:         opts.From,
+		To:           opts.To,
+		Environments: map[ssp.EnvironmentRef]*Metrics{},
+		Stacks:       map[string]*Metrics{},
+		Total:        &Metrics{},
+	}
+
+	for env, deployments := range history {
+		m := &Metrics{}
+		r.Environments[env] = m
+
+		attempted := []*ssp.Deployment{}
+		for _, d := range deployments {
+			if d.DateStarted.Before(opts.From) || d.DateStarted.After(opts.To) {
+				continue
+			}
+			if d.State == ssp.StateCompleted || d.State == ssp.StateFailed || isFailure(d) {
+				attempted = append(attempted, d)
+			}
+		}
+		sort.Slice(attempted, func(i, j int) bool {
+			return attempted[i].DateStarted.Before(attempted[j].DateStarted)
+		})
+
+		var failure *ssp.Deployment
+		for _, d := range attempted {
+			m.Deployments++
+			if isFailure(d) {
+				m.Failures++
+				if failure == nil {
+					failure = d
+				}
+				continue
+			}
+
+			if d.State == ssp.StateCompleted {
+				m.Successful++
+				requested := d.DateRequested
+				if requested.IsZero() {
+					requested = d.DateStarted
+				}
+				m.leadTimes = append(m.leadTimes, d.DateUpdated.Sub(requested))
+			}
+
+			if failure != nil && isRestore(failure, d) {
+				m.Restores++
+				m.restoreTimes = append(m.restoreTimes, d.DateUpdated.Sub(failure.DateUpdated))
+				failure = nil
+			}
+		}
+
+		stack, ok := r.Stacks[env.Stack]
+		if !ok {
+			stack = &Metrics{}
+			r.Stacks[env.Stack] = stack
+		}
+		stack.add(m)
+		r.Total.add(m)
+	}
+
+	days := opts.To.Sub(opts.From).Hours() / 24
+	for _, m := range r.Environments {
+		m.finalise(days)
+	}
+	for _, m := range r.Stacks {
+		m.finalise(days)
+	}
+	r.Total.finalise(days)
+
+	return r, nil
+}
+
+func (o *Options) validate() error {
+	if o == nil || o.From.IsZero() || o.To.IsZero() {
+		return fmt.Errorf("time range is required")
+	}
+	return nil
+}
+
+func (m *Metrics) add(other *Metrics) {
+	m.Deployments += other.Deployments
+	m.Successful += other.Successful
+	m.Failures += other.Failures
+	m.Restores += other.Restores
+	m.leadTimes = append(m.leadTimes, other.leadTimes...)
+	m.restoreTimes = append(m.restoreTimes, other.restoreTimes...)
+}
+
+func (m *Metrics) finalise(days float64) {
+	if days > 0 {
+		m.DeploymentFrequency = float64(m.Successful) / days
+	}
+	if m.Deployments > 0 {
+		m.ChangeFailureRate = float64(m.Failures) / float64(m.Deployments)
+	}
+	m.LeadTime = median(m.leadTimes)
+	m.TimeToRestore = median(m.restoreTimes)
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,139 @@
+package analytics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+var start = time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func deployment(id int, state ssp.State, startedAfter time.Duration, took time.Duration) *ssp.Deployment {
+	started := start.Add(startedAfter)
+	return &ssp.Deployment{
+		ID:            id,
+		State:         state,
+		DateRequested: started.Add(-time.Hour),
+		DateStarted:   started,
+		DateUpdated:   started.Add(took),
+	}
+}
+
+func TestCompute(t *testing.T) {
+	prod := ssp.EnvironmentRef{Stack: "one", Environment: "prod"}
+	uat := ssp.EnvironmentRef{Stack: "one", Environment: "uat"}
+	day := 24 * time.Hour
+
+	history := map[ssp.EnvironmentRef][]*ssp.Deployment{
+		prod: {
+			deployment(1, ssp.StateCompleted, 1*day, time.Hour),
+			deployment(2, ssp.StateFailed, 2*day, time.Hour),
+			deployment(3, ssp.StateCompleted, 2*day+3*time.Hour, time.Hour),
+			deployment(4, ssp.StateCompleted, 3*day, time.Hour),
+			deployment(5, ssp.StateRejected, 4*day, 0),
+			deployment(6, ssp.StateCompleted, 20*day, time.Hour),
+		},
+		uat: {
+			deployment(7, ssp.StateCompleted, 1*day, time.Hour),
+			deployment(8, ssp.StateCompleted, 2*day, time.Hour),
+		},
+	}
+
+	r, err := Compute(history, &Options{From: start, To: start.Add(10 * day)})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	m := r.Environments[prod]
+	if m.Deployments != 4 || m.Successful != 3 || m.Failures != 1 || m.Restores != 1 {
+		t.Errorf("Unexpected prod counts: %+v", m)
+	}
+	if m.ChangeFailureRate != 0.25 {
+		t.Errorf("Unexpected change failure rate %f", m.ChangeFailureRate)
+	}
+	if m.DeploymentFrequency != 0.3 {
+		t.Errorf("Unexpected deployment frequency %f", m.DeploymentFrequency)
+	}
+	if m.LeadTime != 2*time.Hour {
+		t.Errorf("Unexpected lead time %s", m.LeadTime)
+	}
+	if m.TimeToRestore != 3*time.Hour {
+		t.Errorf("Unexpected time to restore %s", m.TimeToRestore)
+	}
+
+	stack := r.Stacks["one"]
+	if stack.Deployments != 6 || stack.Successful != 5 || stack.Failures != 1 {
+		t.Errorf("Unexpected stack rollup: %+v", stack)
+	}
+	if r.Total.Deployments != 6 {
+		t.Errorf("Unexpected total: %+v", r.Total)
+	}
+}
+
+func TestComputeCustomFailure(t *testing.T) {
+	prod := ssp.EnvironmentRef{Stack: "one", Environment: "prod"}
+	hotfix := deployment(2, ssp.StateCompleted, 2*time.Hour, time.Hour)
+	hotfix.Title = "Hotfix"
+
+	history := map[ssp.EnvironmentRef][]*ssp.Deployment{
+		prod: {
+			deployment(1, ssp.StateCompleted, time.Hour, time.Hour),
+			hotfix,
+		},
+	}
+
+	r, err := Compute(history, &Options{
+		From: start,
+		To:   start.Add(24 * time.Hour),
+		IsFailure: func(d *ssp.Deployment) bool {
+			return d.ID == 1
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	m := r.Environments[prod]
+	if m.Failures != 1 || m.Restores != 1 || m.TimeToRestore != time.Hour {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+}
+
+func TestComputeRequiresTimeRange(t *testing.T) {
+	history := map[ssp.EnvironmentRef][]*ssp.Deployment{}
+	if _, err := Compute(history, nil); err == nil {
+		t.Error("Expected an error without options")
+	}
+	if _, err := Compute(history, &Options{From: start}); err == nil {
+		t.Error("Expected an error without an end time")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Add("Content-Type", jsonapi.MediaType)
+		jsonapi.MarshalPayload(w, []*ssp.Deployment{
+			{ID: 1, OriginalState: "Completed", DateStarted: start.Add(time.Hour), DateUpdated: start.Add(2 * time.Hour)},
+		})
+	}))
+	defer ts.Close()
+
+	c, _ := ssp.NewClient(&ssp.Config{BaseURL: ts.URL})
+	prod := ssp.EnvironmentRef{Stack: "one", Environment: "prod"}
+	r, err := Fetch(c, []ssp.EnvironmentRef{prod}, &Options{From: start, To: start.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.Environments[prod].Successful != 1 {
+		t.Errorf("Unexpected metrics: %+v", r.Environments[prod])
+	}
+	if query != "datestarted_from_unix=1488326400&datestarted_to_unix=1488412800" {
+		t.Errorf("Unexpected query '%s'", query)
+	}
+}
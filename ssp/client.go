@@ -23,9 +23,12 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/go-querystring/query"
 	"github.com/google/jsonapi"
 )
 
@@ -85,6 +88,16 @@ func (a *Client) get(path string) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
+// getQuery sends a GET request with params encoded into the query string. See addQuery.
+func (a *Client) getQuery(path string, params interface{}) (io.ReadCloser, error) {
+	path, err := addQuery(path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.get(path)
+}
+
 func (a *Client) post(path string, body io.Reader) (io.ReadCloser, error) {
 	resp, err := a.request("POST", path, body)
 	if err != nil {
@@ -104,19 +117,64 @@ func (a *Client) delete(path string, body io.Reader) (io.ReadCloser, error) {
 }
 
 func (a *Client) request(method string, path string, body io.Reader) (*http.Response, error) {
+	req, err := a.newRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", jsonapi.MediaType)
+
+	resp, err := a.do(req, path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 204 && resp.Header.Get("Content-Type") != "application/vnd.api+json" {
+		return nil, fmt.Errorf("Unexpected Content-Type: '%s'", resp.Header.Get("Content-Type"))
+	}
+
+	return resp, nil
+}
+
+// stream sends a request whose request or response body is not JSON API, such as a file transfer. The caller sets
+// any additional headers, including Content-Length for a streamed body, and must close the response body.
+func (a *Client) stream(method string, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := a.newRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if length := req.Header.Get("Content-Length"); length != "" {
+		req.ContentLength, err = strconv.ParseInt(length, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Del("Content-Length")
+	}
+
+	return a.do(req, path, false)
+}
+
+func (a *Client) newRequest(method string, path string, body io.Reader) (*http.Request, error) {
 	uri := fmt.Sprintf("%s/%s", a.baseURL, path)
 	req, err := http.NewRequest(method, uri, body)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", jsonapi.MediaType)
 	req.Header.Add("Accept", jsonapi.MediaType)
 	req.Header.Add("X-Api-Version", "2.0")
 
+	return req, nil
+}
+
+// do sends the request and converts error statuses into errors. Bodies are only included in debug dumps when
+// dumpBody is set, to avoid buffering file transfers, and secrets are masked in them.
+func (a *Client) do(req *http.Request, path string, dumpBody bool) (*http.Response, error) {
 	if os.Getenv("DEBUG") != "" {
-		dump, _ := httputil.DumpRequestOut(req, true)
-		fmt.Printf("%s", dump)
+		dump, _ := httputil.DumpRequestOut(req, dumpBody)
+		fmt.Printf("%s", maskSecrets(dump))
 	}
 
 	resp, err := a.client.Do(req)
@@ -127,22 +185,37 @@ func (a *Client) request(method string, path string, body io.Reader) (*http.Resp
 	if resp.StatusCode > 299 {
 		er := &ErrorResponse{}
 		if err := json.NewDecoder(resp.Body).Decode(er); err == nil {
-			return nil, fmt.Errorf("%s %s | HTTP %d - '%s'", method, path, resp.StatusCode, er)
+			return nil, fmt.Errorf("%s %s | HTTP %d - '%s'", req.Method, path, resp.StatusCode, er)
 		} else {
-			return nil, fmt.Errorf("%s %s | HTTP %d - '%s'", method, path, resp.StatusCode, resp.Status)
+			return nil, fmt.Errorf("%s %s | HTTP %d - '%s'", req.Method, path, resp.StatusCode, resp.Status)
 		}
 	}
 
 	if os.Getenv("DEBUG") != "" {
-		dump, _ := httputil.DumpResponse(resp, true)
-		fmt.Printf("%s", dump)
+		dump, _ := httputil.DumpResponse(resp, dumpBody)
+		fmt.Printf("%s", maskSecrets(dump))
 	}
 
-	if resp.StatusCode != 204 && resp.Header.Get("Content-Type") != "application/vnd.api+json" {
-		return nil, fmt.Errorf("Unexpected Content-Type: '%s'", resp.Header.Get("Content-Type"))
+	return resp, nil
+}
+
+// addQuery encodes the "url"-tagged fields of params and appends them to the path as a query string.
+// A nil params leaves the path untouched.
+func addQuery(path string, params interface{}) (string, error) {
+	v := reflect.ValueOf(params)
+	if params == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return path, nil
 	}
 
-	return resp, nil
+	q, err := query.Values(params)
+	if err != nil {
+		return "", fmt.Errorf("failed encoding query: '%s'", err)
+	}
+	if len(q) == 0 {
+		return path, nil
+	}
+
+	return fmt.Sprintf("%s?%s", path, q.Encode()), nil
 }
 
 func parseSSTime(t string) (time.Time, error) {
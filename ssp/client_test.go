@@ -2,9 +2,12 @@ package ssp
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/google/jsonapi"
@@ -73,3 +76,87 @@ func newMockDashboard(in interface{}, responseCode int) (*Client, *httptest.Serv
 	}
 	return api, ts
 }
+
+// mockRoute is a canned response served by the mock router for a single "METHOD /path" key. A payload of type
+// func() interface{} is called on each request, and an http.HandlerFunc payload writes the whole response.
+type mockRoute struct {
+	code    int
+	payload interface{}
+}
+
+// mockRequest is a request received by the mock router.
+type mockRequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+}
+
+// mockRouter serves different payloads depending on the request method and path, and records all requests.
+type mockRouter struct {
+	mu       sync.Mutex
+	routes   map[string]mockRoute
+	requests []mockRequest
+}
+
+func newMockRouter(routes map[string]mockRoute) (*Client, *httptest.Server, *mockRouter) {
+	m := &mockRouter{routes: routes}
+	ts := httptest.NewServer(m)
+
+	api, err := NewClient(&Config{BaseURL: ts.URL})
+	if err != nil {
+		panic("Something bad happened while creating the API")
+	}
+	return api, ts, m
+}
+
+func (m *mockRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	key := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+
+	m.mu.Lock()
+	m.requests = append(m.requests, mockRequest{
+		method: r.Method,
+		path:   r.URL.Path,
+		query:  r.URL.Query(),
+		body:   body,
+	})
+	route, ok := m.routes[key]
+	m.mu.Unlock()
+
+	if !ok {
+		w.Header().Add("Content-Type", jsonapi.MediaType)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if h, ok := route.payload.(http.HandlerFunc); ok {
+		h(w, r)
+		return
+	}
+
+	payload := route.payload
+	if f, ok := payload.(func() interface{}); ok {
+		payload = f()
+	}
+
+	w.Header().Add("Content-Type", jsonapi.MediaType)
+	w.WriteHeader(route.code)
+	if payload != nil {
+		jsonapi.MarshalPayload(w, payload)
+	}
+}
+
+// requestsTo returns all recorded requests matching the method and path.
+func (m *mockRouter) requestsTo(method string, path string) []mockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var matching []mockRequest
+	for _, r := range m.requests {
+		if r.method == method && r.path == path {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
@@ -2,25 +2,22 @@ package ssp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/google/go-querystring/query"
 	"github.com/google/jsonapi"
 	"github.com/mitchellh/mapstructure"
 	"io"
 	"reflect"
+	"strconv"
 	"time"
 )
 
 const (
-	UpgradeInfrastructureOption = "upgrade_infrastructure"
-	IgnoreConfigChangeOption    = "ignore_config_changes"
-	ForceFullOption             = "force_full"
-)
-
-const (
-	DeploymentTypeFull     = "full"
-	DeploymentTypeCodeOnly = "code-only"
+	RefTypeBranch = "branch"
+	RefTypeTag    = "tag"
+	RefTypeSHA    = "sha"
 )
 
 type State string
@@ -39,40 +36,74 @@ const (
 	StateDeleted   = "Deleted"
 )
 
+// InProgress reports whether a deployment in this state is queued or currently being deployed.
+func (s State) InProgress() bool {
+	switch s {
+	case StateQueued, StateDeploying, StateAborting:
+		return true
+	}
+	return false
+}
+
+// Final reports whether a deployment in this state will not change state anymore.
+func (s State) Final() bool {
+	switch s {
+	case StateInvalid, StateRejected, StateCompleted, StateFailed, StateDeleted:
+		return true
+	}
+	return false
+}
+
+const (
+	SortDateStarted       = "date_started"
+	SortDateStartedDesc   = "-date_started"
+	SortDateRequested     = "date_requested"
+	SortDateRequestedDesc = "-date_requested"
+	SortLastEdited        = "last_edited"
+	SortLastEditedDesc    = "-last_edited"
+)
+
+// DeploymentFilter narrows down ListDeployments results. Fields left at their zero value are not sent.
 type DeploymentFilter struct {
-	State           State  `url:"state"`
-	LastEditedFrom  int64  `url:"lastedited_from_unix"`
-	DateStartedFrom int64  `url:"datestarted_from_unix"`
-	DateStartedTo   int64  `url:"datestarted_to_unix"`
-	DeployerEmail   string `url:"deployer_email"`
-	Title           string `url:"title"`
-	Summary         string `url:"summary"`
+	States          []State   `url:"state,comma,omitempty"`
+	LastEditedFrom  time.Time `url:"lastedited_from_unix,unix,omitempty"`
+	DateStartedFrom time.Time `url:"datestarted_from_unix,unix,omitempty"`
+	DateStartedTo   time.Time `url:"datestarted_to_unix,unix,omitempty"`
+	DeployerEmail   string    `url:"deployer_email,omitempty"`
+	Title           string    `url:"title,omitempty"`
+	Summary         string    `url:"summary,omitempty"`
+	RefName         string    `url:"ref_name,omitempty"`
+	SHA             string    `url:"sha,omitempty"`
+	Sort            string    `url:"sort,omitempty"`
+	Limit           int       `url:"limit,omitempty"`
 }
 
 type Deployment struct {
-	ID              int       `jsonapi:"primary,deployments"`
-	DateCreated     time.Time `jsonapi:"attr,date_created_unix"`
-	DateStarted     time.Time `jsonapi:"attr,date_started_unix"`
-	DateRequested   time.Time `jsonapi:"attr,date_requested_unix"`
-	DateUpdated     time.Time `jsonapi:"attr,date_updated_unix"`
-	ScheduleStart   time.Time `jsonapi:"attr,schedule_start_unix"`
-	ScheduleEnd     time.Time `jsonapi:"attr,schedule_end_unix"`
-	Title           string    `jsonapi:"attr,title"`
-	Summary         string    `jsonapi:"attr,summary"`
-	RefType         string    `jsonapi:"attr,ref_type"`
-	RefName         string    `jsonapi:"attr,ref_name"`
-	RejectedReason  string    `jsonapi:"attr,rejected_reason"`
-	Tags            []string  `jsonapi:"attr,tags"`
-	DeploymentType  string    `jsonapi:"attr,deployment_type"`
-	SHA             string    `jsonapi:"attr,sha"`
-	ShortSHA        string    `jsonapi:"attr,short_sha"`
-	IsCurrentBuild  bool      `jsonapi:"attr,is_current_build"`
-	Changes         map[string]DeploymentChange
-	OriginalChanges map[string]interface{} `jsonapi:"attr,changes"`
-	State           State
-	OriginalState   string       `jsonapi:"attr,state"`
-	Environment     *Environment `jsonapi:"relation,environment"`
-	Stack           *Stack       `jsonapi:"relation,stack"`
+	ID                     int       `jsonapi:"primary,deployments"`
+	DateCreated            time.Time `jsonapi:"attr,date_created_unix"`
+	DateStarted            time.Time `jsonapi:"attr,date_started_unix"`
+	DateRequested          time.Time `jsonapi:"attr,date_requested_unix"`
+	DateUpdated            time.Time `jsonapi:"attr,date_updated_unix"`
+	ScheduleStart          time.Time `jsonapi:"attr,schedule_start_unix"`
+	ScheduleEnd            time.Time `jsonapi:"attr,schedule_end_unix"`
+	Title                  string    `jsonapi:"attr,title"`
+	Summary                string    `jsonapi:"attr,summary"`
+	RefType                string    `jsonapi:"attr,ref_type"`
+	RefName                string    `jsonapi:"attr,ref_name"`
+	RejectedReason         string    `jsonapi:"attr,rejected_reason"`
+	DeployerEmail          string    `jsonapi:"attr,deployer_email"`
+	Tags                   []string  `jsonapi:"attr,tags"`
+	DeploymentType         DeploymentType
+	SHA                    string `jsonapi:"attr,sha"`
+	ShortSHA               string `jsonapi:"attr,short_sha"`
+	IsCurrentBuild         bool   `jsonapi:"attr,is_current_build"`
+	Changes                map[string]DeploymentChange
+	OriginalChanges        map[string]interface{} `jsonapi:"attr,changes"`
+	State                  State
+	OriginalState          string       `jsonapi:"attr,state"`
+	OriginalDeploymentType string       `jsonapi:"attr,deployment_type"`
+	Environment            *Environment `jsonapi:"relation,environment"`
+	Stack                  *Stack       `jsonapi:"relation,stack"`
 }
 
 type DeploymentChange struct {
@@ -82,16 +113,16 @@ type DeploymentChange struct {
 }
 
 type CreateDeployment struct {
-	Ref            string   `json:"ref"`
-	RefType        string   `json:"ref_type"`
-	Title          string   `json:"title"`
-	Summary        string   `json:"summary"`
-	Options        []string `json:"options"`
-	ScheduleStart  int64    `json:"schedule_start_unix"`
-	ScheduleEnd    int64    `json:"schedule_end_unix"`
-	Bypass         bool     `json:"bypass"`
-	BypassAndStart bool     `json:"bypass_and_start"`
-	Locked         bool     `json:"locked"`
+	Ref            string             `json:"ref"`
+	RefType        string             `json:"ref_type"`
+	Title          string             `json:"title"`
+	Summary        string             `json:"summary"`
+	Options        []DeploymentOption `json:"options"`
+	ScheduleStart  int64              `json:"schedule_start_unix"`
+	ScheduleEnd    int64              `json:"schedule_end_unix"`
+	Bypass         bool               `json:"bypass"`
+	BypassAndStart bool               `json:"bypass_and_start"`
+	Locked         bool               `json:"locked"`
 }
 
 type StartDeployment struct {
@@ -117,16 +148,12 @@ func (a *Client) GetDeploymentCurrentFull(sID string, eID string) (*Deployment,
 }
 
 func (a *Client) ListDeployments(sID string, eID string, filter *DeploymentFilter) ([]*Deployment, error) {
-	url := fmt.Sprintf("naut/project/%s/environment/%s/deploys", sID, eID)
-
-	// todo: move further back to api.Get
-	q, err := query.Values(filter)
-	if err != nil {
-		return nil, err
+	if filter != nil && filter.Limit < 0 {
+		return nil, fmt.Errorf("invalid deployment filter limit %d", filter.Limit)
 	}
-	url += "?" + q.Encode()
 
-	r, err := a.get(url)
+	url := fmt.Sprintf("naut/project/%s/environment/%s/deploys", sID, eID)
+	r, err := a.getQuery(url, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +192,44 @@ func (a *Client) GetDeployment(sID string, eID string, dID string) (*Deployment,
 	return d, nil
 }
 
+// DefaultPollInterval is used by the waiting functions when no positive interval is given.
+const DefaultPollInterval = 5 * time.Second
+
+// WaitForDeployment polls the deployment every interval until it reaches a final state or the context is done.
+// A non-positive interval means DefaultPollInterval.
+func (a *Client) WaitForDeployment(ctx context.Context, sID string, eID string, dID int, interval time.Duration) (*Deployment, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		d, err := a.GetDeployment(sID, eID, strconv.Itoa(dID))
+		if err != nil {
+			return nil, err
+		}
+		if d.State.Final() {
+			return d, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return d, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
 func (a *Client) CreateDeployment(sID string, eID string, cd *CreateDeployment) (*Deployment, error) {
+	if cd == nil {
+		return nil, errors.New("deployment is required")
+	}
+	if err := cd.validateSchedule(time.Now()); err != nil {
+		return nil, err
+	}
+	if err := ValidateDeploymentOptions(cd.Options); err != nil {
+		return nil, err
+	}
+
 	req, err := json.Marshal(cd)
 	if err != nil {
 		return nil, err
@@ -260,6 +324,25 @@ func (a *Client) DeleteDeployment(sID string, eID string, dID int) error {
 	return nil
 }
 
+// DecodeDeployment decodes a JSON API deployment document, such as one received in a webhook, and post-processes
+// it the same way deployments returned by the Client are. The included environment and stack environments are
+// post-processed too.
+func DecodeDeployment(r io.Reader) (*Deployment, error) {
+	d, err := responseToDeployment(r)
+	if err != nil {
+		return nil, err
+	}
+
+	postProcessEnvironment(d.Environment)
+	if d.Stack != nil {
+		for _, env := range d.Stack.Environments {
+			postProcessEnvironment(env)
+		}
+	}
+
+	return d, nil
+}
+
 func responseToDeployment(r io.Reader) (*Deployment, error) {
 	d := &Deployment{}
 	err := jsonapi.UnmarshalPayload(r, d)
@@ -309,5 +392,17 @@ func postProcessDeployment(d *Deployment) error {
 		d.State = state
 	}
 
+	deploymentType, err := ParseDeploymentType(d.OriginalDeploymentType)
+	if err == nil {
+		d.DeploymentType = deploymentType
+	}
+
 	return nil
 }
+
+func shortSHA(d *Deployment) string {
+	if d.ShortSHA != "" {
+		return d.ShortSHA
+	}
+	return shortGitSHA(d.SHA)
+}
@@ -0,0 +1,231 @@
+package ssp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	ChangeKindInfrastructure = "infrastructure"
+	ChangeKindConfig         = "config"
+	ChangeKindCode           = "code"
+	ChangeKindOther          = "other"
+)
+
+// changeKinds maps the change names sent by the Dashboard in Deployment.Changes to their kind. "Infrastructure" is
+// the name of infrastructure upgrades in deployment responses, while the config and code names follow the labels
+// of the Dashboard deployment summary. Any other name is ChangeKindOther, so a change the SDK does not recognise
+// is never mistaken for a code-only change.
+var changeKinds = map[string]ChangeKind{
+	"Infrastructure":            ChangeKindInfrastructure,
+	"Environment configuration": ChangeKindConfig,
+	"Code version":              ChangeKindCode,
+}
+
+// ChangeReport is a stable, renderable view of the changes a deployment introduces.
+//
+// Infrastructure and config changes are listed before code changes, and other changes last. The deployment is
+// CodeOnly only if every change is a code change. Any infrastructure or config changes are reported as implying
+// the ForceFullOption, and infrastructure changes additionally imply the UpgradeInfrastructureOption. Other
+// changes imply no options, but are named in the rendered reports as they may not be code-only.
+type ChangeReport struct {
+	DeploymentID   int                `json:"deployment_id"`
+	DeploymentType DeploymentType     `json:"deployment_type,omitempty"`
+	Changes        []*ReportedChange  `json:"changes"`
+	CodeOnly       bool               `json:"code_only"`
+	ImpliedOptions []DeploymentOption `json:"implied_options"`
+}
+
+// ReportedChange is a single classified entry of Deployment.Changes.
+type ReportedChange struct {
+	Name        string     `json:"name"`
+	Kind        ChangeKind `json:"kind"`
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	Description string     `json:"description,omitempty"`
+}
+
+// NewChangeReport classifies and orders the changes of a deployment.
+func NewChangeReport(d *Deployment) *ChangeReport {
+	r := &ChangeReport{
+		DeploymentID:   d.ID,
+		DeploymentType: d.DeploymentType,
+		Changes:        make([]*ReportedChange, 0, len(d.Changes)),
+		ImpliedOptions: []DeploymentOption{},
+	}
+
+	var infrastructure, config bool
+	r.CodeOnly = true
+	for name, ch := range d.Changes {
+		kind := classifyChange(name)
+		switch kind {
+		case ChangeKindInfrastructure:
+			infrastructure = true
+		case ChangeKindConfig:
+			config = true
+		}
+		if kind != ChangeKindCode {
+			r.CodeOnly = false
+		}
+		r.Changes = append(r.Changes, &ReportedChange{
+			Name:        name,
+			Kind:        kind,
+			From:        ch.From,
+			To:          ch.To,
+			Description: ch.Description,
+		})
+	}
+
+	order := map[ChangeKind]int{
+		ChangeKindInfrastructure: 0,
+		ChangeKindConfig:         1,
+		ChangeKindCode:           2,
+		ChangeKindOther:          3,
+	}
+	sort.Slice(r.Changes, func(i, j int) bool {
+		if r.Changes[i].Kind != r.Changes[j].Kind {
+			return order[r.Changes[i].Kind] < order[r.Changes[j].Kind]
+		}
+		return r.Changes[i].Name < r.Changes[j].Name
+	})
+
+	if infrastructure || config {
+		r.ImpliedOptions = append(r.ImpliedOptions, ForceFullOption)
+	}
+	if infrastructure {
+		r.ImpliedOptions = append(r.ImpliedOptions, UpgradeInfrastructureOption)
+	}
+
+	return r
+}
+
+// Text renders the report as plain text.
+func (r *ChangeReport) Text() string {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "Deployment #%d changes:\n", r.DeploymentID)
+	if len(r.Changes) == 0 {
+		fmt.Fprintf(b, "  No changes.\n")
+	}
+	for _, ch := range r.Changes {
+		fmt.Fprintf(b, "  [%s] %s: %s -> %s", ch.Kind, ch.Name, ch.From, ch.To)
+		if ch.Description != "" {
+			fmt.Fprintf(b, " (%s)", ch.Description)
+		}
+		fmt.Fprintf(b, "\n")
+	}
+	if r.CodeOnly {
+		fmt.Fprintf(b, "Code-only changes.\n")
+	}
+	if len(r.ImpliedOptions) > 0 {
+		options := make([]string, len(r.ImpliedOptions))
+		for i, o := range r.ImpliedOptions {
+			options[i] = string(o)
+		}
+		fmt.Fprintf(b, "Infrastructure or config changes, implies: %s\n", strings.Join(options, ", "))
+	}
+	if other := r.other(); len(other) > 0 {
+		fmt.Fprintf(b, "Other changes, which may not be code-only: %s\n", strings.Join(other, ", "))
+	}
+
+	return b.String()
+}
+
+// Markdown renders the report as a Markdown table, suitable for pull requests and approval requests.
+func (r *ChangeReport) Markdown() string {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "### Deployment #%d changes\n\n", r.DeploymentID)
+	if len(r.Changes) == 0 {
+		fmt.Fprintf(b, "No changes.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(b, "| Type | Change | From | To | Description |\n")
+	fmt.Fprintf(b, "| --- | --- | --- | --- | --- |\n")
+	for _, ch := range r.Changes {
+		name := markdownCell(ch.Name)
+		if ch.Kind == ChangeKindInfrastructure || ch.Kind == ChangeKindConfig {
+			name = fmt.Sprintf("**%s**", name)
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n", ch.Kind, name, markdownCode(ch.From), markdownCode(ch.To), markdownCell(ch.Description))
+	}
+
+	fmt.Fprintf(b, "\n")
+	if r.CodeOnly {
+		fmt.Fprintf(b, "Code-only changes.\n")
+	}
+	if len(r.ImpliedOptions) > 0 {
+		options := make([]string, len(r.ImpliedOptions))
+		for i, o := range r.ImpliedOptions {
+			options[i] = fmt.Sprintf("`%s`", o)
+		}
+		fmt.Fprintf(b, "> :warning: Infrastructure or config changes, implies %s.\n", strings.Join(options, ", "))
+	}
+	if other := r.other(); len(other) > 0 {
+		for i, name := range other {
+			other[i] = markdownCell(name)
+		}
+		fmt.Fprintf(b, "> :warning: Other changes, which may not be code-only: %s.\n", strings.Join(other, ", "))
+	}
+
+	return b.String()
+}
+
+// JSON renders the report as indented JSON.
+func (r *ChangeReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// other returns the names of the changes of ChangeKindOther, in report order.
+func (r *ChangeReport) other() []string {
+	names := []string{}
+	for _, ch := range r.Changes {
+		if ch.Kind == ChangeKindOther {
+			names = append(names, ch.Name)
+		}
+	}
+	return names
+}
+
+func classifyChange(name string) ChangeKind {
+	if kind, ok := changeKinds[name]; ok {
+		return kind
+	}
+	return ChangeKindOther
+}
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "`", "\\`", -1)
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// markdownCode renders text as a code span in a Markdown table cell. The fence is one backtick longer than the
+// longest run of backticks in the text, so they cannot close it early.
+func markdownCode(s string) string {
+	s = strings.Replace(s, "|", "\\|", -1)
+	s = strings.Replace(s, "\n", " ", -1)
+
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if longest > 0 {
+		return fmt.Sprintf("%s %s %s", fence, s, fence)
+	}
+	return fence + s + fence
+}
@@ -0,0 +1,106 @@
+package ssp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewChangeReport(t *testing.T) {
+	d := &Deployment{
+		ID: 5,
+		Changes: map[string]DeploymentChange{
+			"Code version":              {From: "abc", To: "def"},
+			"Infrastructure":            {From: "5", To: "6", Description: "Changed"},
+			"Environment configuration": {From: "a", To: "b"},
+		},
+	}
+
+	r := NewChangeReport(d)
+	names := []string{}
+	for _, ch := range r.Changes {
+		names = append(names, ch.Name)
+	}
+	if strings.Join(names, ",") != "Infrastructure,Environment configuration,Code version" {
+		t.Errorf("Unexpected change order: %v", names)
+	}
+	if r.CodeOnly {
+		t.Error("Report should not be code-only")
+	}
+	if len(r.ImpliedOptions) != 2 || r.ImpliedOptions[0] != ForceFullOption || r.ImpliedOptions[1] != UpgradeInfrastructureOption {
+		t.Errorf("Unexpected implied options: %v", r.ImpliedOptions)
+	}
+
+	text := r.Text()
+	if !strings.Contains(text, "[infrastructure] Infrastructure: 5 -> 6 (Changed)") {
+		t.Errorf("Unexpected text report:\n%s", text)
+	}
+
+	md := r.Markdown()
+	if !strings.Contains(md, "| infrastructure | **Infrastructure** | `5` | `6` | Changed |") {
+		t.Errorf("Unexpected markdown report:\n%s", md)
+	}
+
+	raw, err := r.JSON()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	decoded := &ChangeReport{}
+	if err := json.Unmarshal(raw, decoded); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if decoded.DeploymentID != 5 || len(decoded.Changes) != 3 {
+		t.Error("JSON report does not round-trip")
+	}
+}
+
+func TestNewChangeReportCodeOnly(t *testing.T) {
+	d := &Deployment{
+		ID: 6,
+		Changes: map[string]DeploymentChange{
+			"Code version": {From: "abc", To: "def"},
+		},
+	}
+
+	r := NewChangeReport(d)
+	if !r.CodeOnly {
+		t.Error("Report should be code-only")
+	}
+	if len(r.ImpliedOptions) != 0 {
+		t.Errorf("Unexpected implied options: %v", r.ImpliedOptions)
+	}
+}
+
+func TestNewChangeReportOther(t *testing.T) {
+	d := &Deployment{
+		ID: 7,
+		Changes: map[string]DeploymentChange{
+			"Code version": {From: "abc", To: "def"},
+			"Branch":       {From: "master", To: "release`1`"},
+			"Repository":   {From: "a|b", To: "c", Description: "Moved to `c`"},
+		},
+	}
+
+	r := NewChangeReport(d)
+	if r.Changes[0].Kind != ChangeKindCode || r.Changes[1].Kind != ChangeKindOther || r.Changes[2].Kind != ChangeKindOther {
+		t.Errorf("Unknown changes should be listed last as other: %v", r.Changes)
+	}
+	if r.CodeOnly || len(r.ImpliedOptions) != 0 {
+		t.Errorf("Other changes should not be code-only and imply no options, got %v", r.ImpliedOptions)
+	}
+	text := r.Text()
+	if strings.Contains(text, "Code-only") || !strings.Contains(text, "Other changes, which may not be code-only: Branch, Repository\n") {
+		t.Errorf("Other changes should be named:\n%s", text)
+	}
+
+	md := r.Markdown()
+	if !strings.Contains(md, "| other | Branch | `master` | `` release`1` `` |  |") {
+		t.Errorf("Backticks in values should be fenced:\n%s", md)
+	}
+	if !strings.Contains(md, "| other | Repository | `a\\|b` | `c` | Moved to \\`c\\` |") {
+		t.Errorf("Cells should be escaped:\n%s", md)
+	}
+	if strings.Contains(md, "Code-only") || !strings.Contains(md, "> :warning: Other changes, which may not be code-only: Branch, Repository.") {
+		t.Errorf("Other changes should be named:\n%s", md)
+	}
+}
@@ -0,0 +1,74 @@
+package ssp
+
+import (
+	"fmt"
+)
+
+type DeploymentOption string
+
+const (
+	UpgradeInfrastructureOption DeploymentOption = "upgrade_infrastructure"
+	IgnoreConfigChangeOption    DeploymentOption = "ignore_config_changes"
+	ForceFullOption             DeploymentOption = "force_full"
+)
+
+type DeploymentType string
+
+const (
+	DeploymentTypeFull     DeploymentType = "full"
+	DeploymentTypeCodeOnly DeploymentType = "code-only"
+)
+
+var deploymentOptions = map[string]DeploymentOption{
+	"upgrade_infrastructure": UpgradeInfrastructureOption,
+	"ignore_config_changes":  IgnoreConfigChangeOption,
+	"force_full":             ForceFullOption,
+}
+
+var deploymentTypes = map[string]DeploymentType{
+	"full":      DeploymentTypeFull,
+	"code-only": DeploymentTypeCodeOnly,
+}
+
+// ParseDeploymentOption converts a string into a DeploymentOption, rejecting unknown options.
+func ParseDeploymentOption(s string) (DeploymentOption, error) {
+	o, ok := deploymentOptions[s]
+	if !ok {
+		return "", fmt.Errorf("unknown deployment option '%s'", s)
+	}
+	return o, nil
+}
+
+// ParseDeploymentType converts a string into a DeploymentType, rejecting unknown types.
+func ParseDeploymentType(s string) (DeploymentType, error) {
+	t, ok := deploymentTypes[s]
+	if !ok {
+		return "", fmt.Errorf("unknown deployment type '%s'", s)
+	}
+	return t, nil
+}
+
+// ValidateDeploymentOptions rejects unknown and repeated options, and combinations that contradict each other:
+// ignoring config changes cannot be combined with forcing a full deployment or upgrading infrastructure.
+func ValidateDeploymentOptions(options []DeploymentOption) error {
+	seen := map[DeploymentOption]bool{}
+	for _, o := range options {
+		if _, err := ParseDeploymentOption(string(o)); err != nil {
+			return err
+		}
+		if seen[o] {
+			return fmt.Errorf("deployment option '%s' given more than once", o)
+		}
+		seen[o] = true
+	}
+
+	if seen[IgnoreConfigChangeOption] {
+		for _, conflicting := range []DeploymentOption{ForceFullOption, UpgradeInfrastructureOption} {
+			if seen[conflicting] {
+				return fmt.Errorf("deployment options '%s' and '%s' contradict each other", IgnoreConfigChangeOption, conflicting)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package ssp
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseDeploymentOption(t *testing.T) {
+	o, err := ParseDeploymentOption("force_full")
+	if err != nil || o != ForceFullOption {
+		t.Errorf("Expected ForceFullOption, got '%s' (%v)", o, err)
+	}
+	if _, err := ParseDeploymentOption("make_it_fast"); err == nil {
+		t.Error("Expected error for unknown option")
+	}
+}
+
+func TestParseDeploymentType(t *testing.T) {
+	dt, err := ParseDeploymentType("code-only")
+	if err != nil || dt != DeploymentTypeCodeOnly {
+		t.Errorf("Expected DeploymentTypeCodeOnly, got '%s' (%v)", dt, err)
+	}
+	if _, err := ParseDeploymentType("partial"); err == nil {
+		t.Error("Expected error for unknown type")
+	}
+}
+
+func TestValidateDeploymentOptions(t *testing.T) {
+	valid := [][]DeploymentOption{
+		nil,
+		{ForceFullOption},
+		{ForceFullOption, UpgradeInfrastructureOption},
+		{IgnoreConfigChangeOption},
+	}
+	for _, options := range valid {
+		if err := ValidateDeploymentOptions(options); err != nil {
+			t.Errorf("Options %v should be valid: %s", options, err)
+		}
+	}
+
+	invalid := [][]DeploymentOption{
+		{"make_it_fast"},
+		{ForceFullOption, ForceFullOption},
+		{IgnoreConfigChangeOption, ForceFullOption},
+		{UpgradeInfrastructureOption, IgnoreConfigChangeOption},
+	}
+	for _, options := range invalid {
+		if err := ValidateDeploymentOptions(options); err == nil {
+			t.Errorf("Options %v should be rejected", options)
+		}
+	}
+}
+
+func TestCreateDeploymentInvalidOptions(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/one/environment/prod/deploys": {http.StatusCreated, &Deployment{}},
+	})
+	defer ts.Close()
+
+	_, err := api.CreateDeployment("one", "prod", &CreateDeployment{
+		Options: []DeploymentOption{IgnoreConfigChangeOption, ForceFullOption},
+	})
+	if err == nil {
+		t.Error("Expected error for contradictory options")
+	}
+	if len(m.requestsTo("POST", "/naut/project/one/environment/prod/deploys")) != 0 {
+		t.Error("Invalid deployment should not be sent")
+	}
+}
@@ -1,8 +1,10 @@
 package ssp
 
 import (
+	"context"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestListDeployments(t *testing.T) {
@@ -25,14 +27,56 @@ func TestListDeployments(t *testing.T) {
 	}
 }
 
+func TestListDeploymentsFilter(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/deploys": {http.StatusOK, []*Deployment{}},
+	})
+	defer ts.Close()
+
+	_, err := api.ListDeployments("one", "prod", &DeploymentFilter{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	_, err = api.ListDeployments("one", "prod", &DeploymentFilter{
+		States:         []State{StateCompleted, StateFailed},
+		LastEditedFrom: time.Unix(1500000000, 0),
+		RefName:        "master",
+		Sort:           SortDateStartedDesc,
+		Limit:          10,
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	reqs := m.requestsTo("GET", "/naut/project/one/environment/prod/deploys")
+	if len(reqs[0].query) != 0 {
+		t.Errorf("Empty filter should not send a query, got '%s'", reqs[0].query.Encode())
+	}
+	expected := "lastedited_from_unix=1500000000&limit=10&ref_name=master&sort=-date_started&state=Completed%2CFailed"
+	if reqs[1].query.Encode() != expected {
+		t.Errorf("Expected query '%s', got '%s'", expected, reqs[1].query.Encode())
+	}
+}
+
+func TestListDeploymentsInvalidLimit(t *testing.T) {
+	api, ts := newMockDashboard([]*Deployment{}, http.StatusOK)
+	defer ts.Close()
+
+	_, err := api.ListDeployments("one", "prod", &DeploymentFilter{Limit: -1})
+	if err == nil {
+		t.Error("Expected error for negative limit")
+	}
+}
+
 func TestGetDeployment(t *testing.T) {
 	changes := make(map[string]interface{})
 	changes["Infrastructure"] = &DeploymentChange{From: "5", To: "6", Description: "Changed"}
 
 	in := &Deployment{
-		ID:              123,
-		OriginalChanges: map[string]interface{}(changes),
-		OriginalState:   "Completed",
+		ID:                     123,
+		OriginalChanges:        map[string]interface{}(changes),
+		OriginalState:          "Completed",
+		OriginalDeploymentType: "code-only",
 	}
 	api, ts := newMockDashboard(in, http.StatusOK)
 	defer ts.Close()
@@ -47,6 +91,9 @@ func TestGetDeployment(t *testing.T) {
 	if out.State != StateCompleted {
 		t.Error("State parsed incorrectly")
 	}
+	if out.DeploymentType != DeploymentTypeCodeOnly {
+		t.Error("DeploymentType parsed incorrectly")
+	}
 	ch, ok := out.Changes["Infrastructure"]
 	if !ok {
 		t.Error("Changes parsed incorrectly")
@@ -100,3 +147,20 @@ func TestDeleteDeployment(t *testing.T) {
 		t.Errorf("%s", err)
 	}
 }
+
+func TestWaitForDeploymentDefaultInterval(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/deploys/1": {http.StatusOK, &Deployment{ID: 1, OriginalState: "Deploying"}},
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := api.WaitForDeployment(ctx, "one", "prod", 1, 0); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if polls := len(m.requestsTo("GET", "/naut/project/one/environment/prod/deploys/1")); polls != 1 {
+		t.Errorf("Zero interval should not busy-loop, got %d polls", polls)
+	}
+}
@@ -3,7 +3,10 @@ package ssp
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
 	"time"
 
 	"github.com/blang/semver"
@@ -19,19 +22,45 @@ const (
 	UsageUnspecified = "Unspecified"
 )
 
+var weekdays = map[string]time.Weekday{
+	"Sunday":    time.Sunday,
+	"Monday":    time.Monday,
+	"Tuesday":   time.Tuesday,
+	"Wednesday": time.Wednesday,
+	"Thursday":  time.Thursday,
+	"Friday":    time.Friday,
+	"Saturday":  time.Saturday,
+}
+
+var usages = map[string]Usage{
+	"Production":  UsageProduction,
+	"UAT":         UsageUAT,
+	"Test":        UsageTest,
+	"Unspecified": UsageUnspecified,
+}
+
 type Environment struct {
-	ID                     string `jsonapi:"primary,environments"`
-	Name                   string `jsonapi:"attr,name"`
-	BuildSHA               string `jsonapi:"attr,build_sha"`
-	BuildPackage           string `jsonapi:"attr,build_package"`
-	MaintenanceDay         time.Weekday
+	ID                string `jsonapi:"primary,environments"`
+	Name              string `jsonapi:"attr,name"`
+	BuildSHA          string `jsonapi:"attr,build_sha"`
+	BuildPackage      string `jsonapi:"attr,build_package"`
+	MaintenanceWindow MaintenanceWindow
+	// Deprecated: use MaintenanceWindow.Day instead.
+	MaintenanceDay time.Weekday
+	// Deprecated: use MaintenanceWindow.Specified instead.
 	MaintenanceUnspecified bool
-	MaintenanceTime        time.Time
-	MaintenanceDuration    time.Duration
-	MaintenanceTz          *time.Location
-	// TODO nothing beyond X.Y.Z is supported. What about 2.3? Or composer constraints?
+	// Deprecated: use MaintenanceWindow.Start instead.
+	MaintenanceTime time.Time
+	// Deprecated: use MaintenanceWindow.Duration instead.
+	MaintenanceDuration time.Duration
+	// Deprecated: use MaintenanceWindow.Location instead.
+	MaintenanceTz *time.Location
+	// The manifest SHAs are parsed with ParseManifestVersion. The Valid fields are false if the original could not
+	// be parsed, in which case the version is zero.
 	DesiredManifestSha          semver.Version
+	DesiredManifestShaValid     bool
 	CurrentManifestSha          semver.Version
+	CurrentManifestShaValid     bool
 	Stack                       *Stack       `jsonapi:"relation,stack"`
 	BaseEnvironment             *Environment `jsonapi:"relation,base_environment"`
 	Usage                       Usage
@@ -46,11 +75,28 @@ type Environment struct {
 	OriginalCurrentManifestSha  string `jsonapi:"attr,current_manifest_sha"`
 }
 
+// EnvironmentRef identifies an environment by its stack and environment IDs, as used in API URLs.
+type EnvironmentRef struct {
+	Stack       string
+	Environment string
+}
+
+func (r EnvironmentRef) String() string {
+	return fmt.Sprintf("%s/%s", r.Stack, r.Environment)
+}
+
 type UpdateInstanceType struct {
 	InstanceType string `json:"instanceType"`
 }
 
+// UpdateInstanceType requests a resize of the environment. The instance type must be one of ListInstanceTypes,
+// which is fetched with an extra request before the resize is sent. The resize happens in the background, use
+// WaitForInstanceType to wait for it to finish.
 func (a *Client) UpdateInstanceType(sID string, eID string, updateData *UpdateInstanceType) error {
+	if err := a.validateInstanceType(sID, eID, updateData.InstanceType); err != nil {
+		return err
+	}
+
 	req, err := json.Marshal(updateData)
 	if err != nil {
 		return err
@@ -66,9 +112,112 @@ func (a *Client) UpdateInstanceType(sID string, eID string, updateData *UpdateIn
 	return nil
 }
 
-func (a *Client) GetEnvironment(sID string, eID string) (*Environment, error) {
+// UpdateEnvironment changes environment settings. Empty fields are left unchanged and are not sent, so a setting
+// cannot be cleared through this API. Values use the Dashboard formats: a weekday name for MaintenanceDay, "15:04"
+// for MaintenanceTime and MaintenanceDuration, an IANA timezone such as "Pacific/Auckland" for MaintenanceTz, and
+// "X.Y" for PHPVersion.
+type UpdateEnvironment struct {
+	MaintenanceDay      string `json:"maintenanceDay,omitempty"`
+	MaintenanceTime     string `json:"maintenanceTime,omitempty"`
+	MaintenanceDuration string `json:"maintenanceDuration,omitempty"`
+	MaintenanceTz       string `json:"maintenanceTz,omitempty"`
+	PHPVersion          string `json:"phpVersion,omitempty"`
+	Usage               Usage  `json:"usage,omitempty"`
+}
+
+var phpVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// SetMaintenanceWindow fills all maintenance fields from the window. The location must be loaded from an IANA
+// timezone name, as time.Local and fixed zones mean nothing to the Dashboard.
+func (ue *UpdateEnvironment) SetMaintenanceWindow(w MaintenanceWindow) error {
+	if !w.Specified() {
+		return ErrMaintenanceUnspecified
+	}
+	if w.Duration >= 24*time.Hour {
+		return fmt.Errorf("maintenance duration %s is too long", w.Duration)
+	}
+	if err := validateTimezone(w.Location.String()); err != nil {
+		return err
+	}
+
+	ue.MaintenanceDay = w.Day.String()
+	ue.MaintenanceTime = clock(w.Start)
+	ue.MaintenanceDuration = clock(w.Duration)
+	ue.MaintenanceTz = w.Location.String()
+	return nil
+}
+
+func (ue *UpdateEnvironment) validate() error {
+	if ue == nil {
+		return errors.New("environment update is required")
+	}
+	if *ue == (UpdateEnvironment{}) {
+		return errors.New("nothing to update")
+	}
+	if _, ok := weekdays[ue.MaintenanceDay]; ue.MaintenanceDay != "" && !ok {
+		return fmt.Errorf("invalid maintenance day '%s'", ue.MaintenanceDay)
+	}
+	if _, err := parseSSTime(ue.MaintenanceTime); ue.MaintenanceTime != "" && err != nil {
+		return fmt.Errorf("invalid maintenance time '%s'", ue.MaintenanceTime)
+	}
+	if ue.MaintenanceDuration != "" {
+		dur, err := parseSSTime(ue.MaintenanceDuration)
+		if err != nil || sinceMidnight(dur) == 0 {
+			return fmt.Errorf("invalid maintenance duration '%s'", ue.MaintenanceDuration)
+		}
+	}
+	if ue.MaintenanceTz != "" {
+		if err := validateTimezone(ue.MaintenanceTz); err != nil {
+			return err
+		}
+	}
+	if ue.PHPVersion != "" && !phpVersionPattern.MatchString(ue.PHPVersion) {
+		return fmt.Errorf("invalid PHP version '%s'", ue.PHPVersion)
+	}
+	if _, ok := usages[string(ue.Usage)]; ue.Usage != "" && !ok {
+		return fmt.Errorf("invalid usage '%s'", ue.Usage)
+	}
+	return nil
+}
+
+// validateTimezone accepts only IANA timezone names. time.LoadLocation also accepts "Local" and "", which the
+// Dashboard would not understand.
+func validateTimezone(name string) error {
+	if name == "" || name == "Local" {
+		return fmt.Errorf("invalid maintenance timezone '%s', an IANA name is required", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid maintenance timezone '%s'", name)
+	}
+	return nil
+}
+
+// UpdateEnvironment validates and applies the changes, and returns the refreshed environment.
+func (a *Client) UpdateEnvironment(sID string, eID string, ue *UpdateEnvironment) (*Environment, error) {
+	if err := ue.validate(); err != nil {
+		return nil, err
+	}
+
+	req, err := json.Marshal(ue)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("naut/project/%s/environment/%s/sspattributes", sID, eID)
+	r, err := a.post(url, bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed updating environment: '%s'", err)
+	}
+	r.Close()
+
+	return a.GetEnvironment(sID, eID)
+}
+
+// GetEnvironment returns a single environment. Related resources, such as IncludeBaseEnvironment, can be fetched
+// in the same request.
+func (a *Client) GetEnvironment(sID string, eID string, include ...string) (*Environment, error) {
 	url := fmt.Sprintf("naut/project/%s/environment/%s", sID, eID)
-	r, err := a.get(url)
+	r, err := a.getQuery(url, &includeQuery{Include: include})
 	if err != nil {
 		return nil, err
 	}
@@ -79,17 +228,42 @@ func (a *Client) GetEnvironment(sID string, eID string) (*Environment, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed unmarshaling environment: '%s'", err)
 	}
+	postProcessEnvironment(env)
 
-	var days = map[string]time.Weekday{
-		"Sunday":    time.Sunday,
-		"Monday":    time.Monday,
-		"Tuesday":   time.Tuesday,
-		"Wednesday": time.Wednesday,
-		"Thursday":  time.Thursday,
-		"Friday":    time.Friday,
-		"Saturday":  time.Saturday,
+	return env, nil
+}
+
+// ListEnvironments returns all environments of the stack. Related resources, such as IncludeStack, can be fetched
+// in the same request.
+func (a *Client) ListEnvironments(sID string, include ...string) ([]*Environment, error) {
+	url := fmt.Sprintf("naut/project/%s/environments", sID)
+	r, err := a.getQuery(url, &includeQuery{Include: include})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	items, err := jsonapi.UnmarshalManyPayload(r, reflect.TypeOf(new(Environment)))
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling environments: '%s'", err)
+	}
+
+	envs := make([]*Environment, len(items))
+	for i, item := range items {
+		envs[i] = item.(*Environment)
+		postProcessEnvironment(envs[i])
+	}
+
+	return envs, nil
+}
+
+// postProcessEnvironment converts the original attributes into typed fields, including on related environments.
+func postProcessEnvironment(env *Environment) {
+	if env == nil {
+		return
 	}
-	weekday, ok := days[env.OriginalMaintenanceDay]
+
+	weekday, ok := weekdays[env.OriginalMaintenanceDay]
 	if ok {
 		env.MaintenanceDay = weekday
 		env.MaintenanceUnspecified = false
@@ -97,6 +271,7 @@ func (a *Client) GetEnvironment(sID string, eID string) (*Environment, error) {
 		env.MaintenanceUnspecified = true
 	}
 
+	var err error
 	env.MaintenanceTime, err = parseSSTime(env.OriginalMaintenanceTime)
 	if err != nil {
 		env.MaintenanceUnspecified = true
@@ -106,28 +281,41 @@ func (a *Client) GetEnvironment(sID string, eID string) (*Environment, error) {
 	if err != nil {
 		env.MaintenanceUnspecified = true
 	}
-	env.MaintenanceDuration = time.Duration(dur.Hour()) * time.Hour
-	env.MaintenanceDuration += time.Duration(dur.Minute()) * time.Minute
-	env.MaintenanceDuration += time.Duration(dur.Second()) * time.Second
+	env.MaintenanceDuration = sinceMidnight(dur)
 
-	env.MaintenanceTz, err = time.LoadLocation(env.OriginalMaintenanceTz)
-	if err != nil {
+	env.MaintenanceTz, _ = time.LoadLocation(env.OriginalMaintenanceTz)
+	if validateTimezone(env.OriginalMaintenanceTz) != nil {
 		env.MaintenanceUnspecified = true
 	}
 
-	var usages = map[string]Usage{
-		"Production":  UsageProduction,
-		"UAT":         UsageUAT,
-		"Test":        UsageTest,
-		"Unspecified": UsageUnspecified,
+	env.MaintenanceWindow = MaintenanceWindow{}
+	if !env.MaintenanceUnspecified {
+		env.MaintenanceWindow = MaintenanceWindow{
+			Day:      env.MaintenanceDay,
+			Start:    sinceMidnight(env.MaintenanceTime),
+			Duration: env.MaintenanceDuration,
+			Location: env.MaintenanceTz,
+		}
 	}
+
 	usage, ok := usages[env.OriginalUsage]
 	if ok {
 		env.Usage = usage
 	}
 
-	env.CurrentManifestSha, _ = semver.Make(env.OriginalCurrentManifestSha)
-	env.DesiredManifestSha, _ = semver.Make(env.OriginalDesiredManifestSha)
+	env.CurrentManifestSha, err = ParseManifestVersion(env.OriginalCurrentManifestSha)
+	env.CurrentManifestShaValid = err == nil
+	env.DesiredManifestSha, err = ParseManifestVersion(env.OriginalDesiredManifestSha)
+	env.DesiredManifestShaValid = err == nil
 
-	return env, nil
+	postProcessEnvironment(env.BaseEnvironment)
+	if env.Stack != nil {
+		for _, e := range env.Stack.Environments {
+			postProcessEnvironment(e)
+		}
+	}
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
 }
@@ -59,7 +59,10 @@ func TestGetEnvironment(t *testing.T) {
 }
 
 func TestUpdateInstanceType(t *testing.T) {
-	api, ts := newMockDashboard(&Environment{}, http.StatusOK)
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/instancetypes":  {http.StatusOK, []*InstanceType{{ID: "1", Name: "t2.nano"}}},
+		"POST /naut/project/one/environment/prod/sspattributes": {http.StatusOK, nil},
+	})
 	defer ts.Close()
 	s := &UpdateInstanceType{
 		InstanceType: "t2.nano",
@@ -72,7 +75,7 @@ func TestUpdateInstanceType(t *testing.T) {
 
 func TestGetEnvironmentUnspecifiedMaintenance(t *testing.T) {
 	in := &Environment{
-		ID: "one",
+		ID:                          "one",
 		OriginalMaintenanceDay:      "Unspecified",
 		OriginalMaintenanceTz:       "Europe/Vatican",
 		OriginalMaintenanceDuration: "2:34",
@@ -88,3 +91,113 @@ func TestGetEnvironmentUnspecifiedMaintenance(t *testing.T) {
 		t.Error("Maintenance should be unspecified")
 	}
 }
+
+func TestListEnvironments(t *testing.T) {
+	in := []*Environment{
+		{ID: "prod", OriginalUsage: "Production", OriginalMaintenanceTz: "Pacific/Auckland", Stack: &Stack{ID: "one", Title: "One"}},
+		{ID: "uat", OriginalUsage: "UAT", OriginalDesiredManifestSha: "2.3.0", BaseEnvironment: &Environment{ID: "prod", OriginalUsage: "Production"}},
+	}
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environments": {http.StatusOK, in},
+	})
+	defer ts.Close()
+
+	out, err := api.ListEnvironments("one", IncludeStack, IncludeBaseEnvironment)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("Expected 2 environments, got %d", len(out))
+	}
+	if out[0].Usage != UsageProduction || out[0].MaintenanceTz.String() != "Pacific/Auckland" {
+		t.Error("Environment was not post-processed")
+	}
+	if out[0].Stack == nil || out[0].Stack.Title != "One" {
+		t.Error("Included stack was not decoded")
+	}
+	if out[1].DesiredManifestSha.String() != "2.3.0" {
+		t.Error("DesiredManifestSha parsed incorrectly")
+	}
+	if out[1].BaseEnvironment == nil || out[1].BaseEnvironment.Usage != UsageProduction {
+		t.Error("Included base environment was not post-processed")
+	}
+
+	reqs := m.requestsTo("GET", "/naut/project/one/environments")
+	if len(reqs) != 1 || reqs[0].query.Get("include") != "stack,base_environment" {
+		t.Errorf("Expected include=stack,base_environment, got %v", reqs)
+	}
+}
+
+func TestUpdateEnvironment(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/one/environment/prod/sspattributes": {http.StatusOK, nil},
+		"GET /naut/project/one/environment/prod":                {http.StatusOK, &Environment{ID: "prod", PHPVersion: "7.1", OriginalUsage: "UAT"}},
+	})
+	defer ts.Close()
+
+	out, err := api.UpdateEnvironment("one", "prod", &UpdateEnvironment{PHPVersion: "7.1", Usage: UsageUAT})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if out.PHPVersion != "7.1" || out.Usage != UsageUAT {
+		t.Error("Refreshed environment not returned")
+	}
+
+	reqs := m.requestsTo("POST", "/naut/project/one/environment/prod/sspattributes")
+	if len(reqs) != 1 {
+		t.Fatalf("Expected 1 update, got %d", len(reqs))
+	}
+	if string(reqs[0].body) != `{"phpVersion":"7.1","usage":"UAT"}` {
+		t.Errorf("Unexpected update %s", reqs[0].body)
+	}
+}
+
+func TestUpdateEnvironmentMaintenanceWindow(t *testing.T) {
+	loc, _ := time.LoadLocation("Pacific/Auckland")
+	ue := &UpdateEnvironment{}
+	err := ue.SetMaintenanceWindow(MaintenanceWindow{Day: time.Wednesday, Start: 22*time.Hour + 30*time.Minute, Duration: 90 * time.Minute, Location: loc})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := UpdateEnvironment{MaintenanceDay: "Wednesday", MaintenanceTime: "22:30", MaintenanceDuration: "01:30", MaintenanceTz: "Pacific/Auckland"}
+	if *ue != expected {
+		t.Errorf("Unexpected update %+v", ue)
+	}
+	if err := ue.validate(); err != nil {
+		t.Errorf("%s", err)
+	}
+
+	if err := ue.SetMaintenanceWindow(MaintenanceWindow{}); err != ErrMaintenanceUnspecified {
+		t.Errorf("Expected ErrMaintenanceUnspecified, got %v", err)
+	}
+	for _, loc := range []*time.Location{time.Local, time.FixedZone("NZST", 12*60*60)} {
+		if err := ue.SetMaintenanceWindow(MaintenanceWindow{Day: time.Wednesday, Duration: time.Hour, Location: loc}); err == nil {
+			t.Errorf("Expected error for location %s", loc)
+		}
+	}
+}
+
+func TestUpdateEnvironmentInvalid(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{})
+	defer ts.Close()
+
+	invalid := []*UpdateEnvironment{
+		nil,
+		{},
+		{MaintenanceDay: "Someday"},
+		{MaintenanceTime: "25:00"},
+		{MaintenanceDuration: "0:00"},
+		{MaintenanceTz: "Mars/Olympus"},
+		{MaintenanceTz: "Local"},
+		{PHPVersion: "seven"},
+		{Usage: "Staging"},
+	}
+	for _, ue := range invalid {
+		if _, err := api.UpdateEnvironment("one", "prod", ue); err == nil {
+			t.Errorf("Expected error for %+v", ue)
+		}
+	}
+	if len(m.requestsTo("POST", "/naut/project/one/environment/prod/sspattributes")) != 0 {
+		t.Error("Invalid updates should not be sent")
+	}
+}
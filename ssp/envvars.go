@@ -0,0 +1,314 @@
+package ssp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/jsonapi"
+)
+
+// MaskedValue replaces environment variable values whenever the SDK prints or logs them.
+const MaskedValue = "****"
+
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// maskedValuePattern matches the JSON "value" members sent and received by the environment variables API.
+var maskedValuePattern = regexp.MustCompile(`("value"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// maskSecrets hides environment variable values in debug dumps.
+func maskSecrets(dump []byte) []byte {
+	return maskedValuePattern.ReplaceAll(dump, []byte(`${1}"`+MaskedValue+`"`))
+}
+
+// EnvironmentVariable is a variable set on an environment. Printing it with fmt masks the value.
+type EnvironmentVariable struct {
+	ID    string `jsonapi:"primary,environment_variables"`
+	Name  string `jsonapi:"attr,name"`
+	Value string `jsonapi:"attr,value"`
+}
+
+func (v EnvironmentVariable) String() string {
+	return fmt.Sprintf("%s=%s", v.Name, MaskedValue)
+}
+
+func (v EnvironmentVariable) GoString() string {
+	return fmt.Sprintf("ssp.EnvironmentVariable{Name:%q, Value:%q}", v.Name, MaskedValue)
+}
+
+type SetEnvironmentVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func validateEnvVarName(name string) error {
+	if !envVarNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name '%s'", name)
+	}
+	return nil
+}
+
+func (a *Client) ListEnvironmentVariables(sID string, eID string) ([]*EnvironmentVariable, error) {
+	url := fmt.Sprintf("naut/project/%s/environment/%s/envvars", sID, eID)
+	r, err := a.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	items, err := jsonapi.UnmarshalManyPayload(r, reflect.TypeOf(new(EnvironmentVariable)))
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling environment variables: '%s'", err)
+	}
+
+	vars := make([]*EnvironmentVariable, len(items))
+	for i, item := range items {
+		vars[i] = item.(*EnvironmentVariable)
+	}
+
+	return vars, nil
+}
+
+func (a *Client) GetEnvironmentVariable(sID string, eID string, name string) (*EnvironmentVariable, error) {
+	if err := validateEnvVarName(name); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("naut/project/%s/environment/%s/envvars/%s", sID, eID, name)
+	r, err := a.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	v := new(EnvironmentVariable)
+	err = jsonapi.UnmarshalPayload(r, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling environment variable: '%s'", err)
+	}
+
+	return v, nil
+}
+
+// SetEnvironmentVariable creates the variable, or changes its value if it already exists.
+func (a *Client) SetEnvironmentVariable(sID string, eID string, sv *SetEnvironmentVariable) error {
+	if err := validateEnvVarName(sv.Name); err != nil {
+		return err
+	}
+
+	req, err := json.Marshal(sv)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("naut/project/%s/environment/%s/envvars", sID, eID)
+	r, err := a.post(url, bytes.NewReader(req))
+	if err != nil {
+		return fmt.Errorf("failed setting environment variable %s: '%s'", sv.Name, err)
+	}
+	defer r.Close()
+
+	return nil
+}
+
+func (a *Client) DeleteEnvironmentVariable(sID string, eID string, name string) error {
+	if err := validateEnvVarName(name); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("naut/project/%s/environment/%s/envvars/%s", sID, eID, name)
+	r, err := a.delete(url, nil)
+	if err != nil {
+		return fmt.Errorf("failed deleting environment variable %s: '%s'", name, err)
+	}
+	defer r.Close()
+
+	return nil
+}
+
+// ParseDotEnv reads variables from a .env file. Blank lines and lines starting with "#" are skipped, and an
+// "export " prefix is ignored. Values may be single-quoted, taken literally, or double-quoted, where \n, \", and
+// \\ are unescaped. Unquoted values end at " #", which starts a comment.
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected NAME=value", line)
+		}
+		name := strings.TrimSpace(text[:eq])
+		if err := validateEnvVarName(name); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		value, err := parseDotEnvValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		vars[name] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func parseDotEnvValue(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		return s[1 : end+1], nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		value := &bytes.Buffer{}
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '"':
+				return value.String(), nil
+			case c == '\\' && i+1 < len(s):
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+			default:
+				value.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	}
+
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// EnvironmentVariableChange is a single difference between the variables of an environment and the desired ones.
+// Old is empty for added variables and New is empty for removed ones. Printing it with fmt masks the values.
+type EnvironmentVariableChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// EnvironmentVariableDiff lists the changes needed to turn the variables of an environment into the desired ones,
+// each ordered by name.
+type EnvironmentVariableDiff struct {
+	Added   []*EnvironmentVariableChange
+	Changed []*EnvironmentVariableChange
+	Removed []*EnvironmentVariableChange
+}
+
+// Empty reports whether the environment already has the desired variables.
+func (d *EnvironmentVariableDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// String lists the changes one per line, with the values masked.
+func (d *EnvironmentVariableDiff) String() string {
+	lines := []string{}
+	for _, c := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s=%s", c.Name, MaskedValue))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s=%s", c.Name, MaskedValue))
+	}
+	for _, c := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s", c.Name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c EnvironmentVariableChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Name, MaskedValue, MaskedValue)
+}
+
+func (c EnvironmentVariableChange) GoString() string {
+	return fmt.Sprintf("ssp.EnvironmentVariableChange{Name:%q, Old:%q, New:%q}", c.Name, MaskedValue, MaskedValue)
+}
+
+// DiffEnvironmentVariables compares the current variables of an environment against the desired ones.
+func DiffEnvironmentVariables(current []*EnvironmentVariable, desired map[string]string) *EnvironmentVariableDiff {
+	d := &EnvironmentVariableDiff{
+		Added:   []*EnvironmentVariableChange{},
+		Changed: []*EnvironmentVariableChange{},
+		Removed: []*EnvironmentVariableChange{},
+	}
+
+	existing := map[string]string{}
+	for _, v := range current {
+		existing[v.Name] = v.Value
+		if _, ok := desired[v.Name]; !ok {
+			d.Removed = append(d.Removed, &EnvironmentVariableChange{Name: v.Name, Old: v.Value})
+		}
+	}
+	for name, value := range desired {
+		old, ok := existing[name]
+		if !ok {
+			d.Added = append(d.Added, &EnvironmentVariableChange{Name: name, New: value})
+		} else if old != value {
+			d.Changed = append(d.Changed, &EnvironmentVariableChange{Name: name, Old: old, New: value})
+		}
+	}
+
+	for _, changes := range [][]*EnvironmentVariableChange{d.Added, d.Changed, d.Removed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	}
+	return d
+}
+
+// PlanEnvironmentVariables computes the changes needed for the environment to have exactly the desired variables,
+// for example from ParseDotEnv. Review the diff, then pass it to ApplyEnvironmentVariables.
+func (a *Client) PlanEnvironmentVariables(sID string, eID string, desired map[string]string) (*EnvironmentVariableDiff, error) {
+	for name := range desired {
+		if err := validateEnvVarName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := a.ListEnvironmentVariables(sID, eID)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffEnvironmentVariables(current, desired), nil
+}
+
+// ApplyEnvironmentVariables sets the added and changed variables, then deletes the removed ones. It stops at the
+// first error, leaving the changes before it applied.
+func (a *Client) ApplyEnvironmentVariables(sID string, eID string, d *EnvironmentVariableDiff) error {
+	for _, changes := range [][]*EnvironmentVariableChange{d.Added, d.Changed} {
+		for _, c := range changes {
+			if err := a.SetEnvironmentVariable(sID, eID, &SetEnvironmentVariable{Name: c.Name, Value: c.New}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range d.Removed {
+		if err := a.DeleteEnvironmentVariable(sID, eID, c.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
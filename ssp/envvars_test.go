@@ -0,0 +1,128 @@
+package ssp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	vars, err := ParseDotEnv(strings.NewReader(`
+# Database
+export DB_HOST=db.internal
+DB_PASSWORD='p@ss #word'
+GREETING="hello\n\"world\""
+EMPTY=
+FLAG=on # enabled for now
+`))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	expected := map[string]string{
+		"DB_HOST":     "db.internal",
+		"DB_PASSWORD": "p@ss #word",
+		"GREETING":    "hello\n\"world\"",
+		"EMPTY":       "",
+		"FLAG":        "on",
+	}
+	if len(vars) != len(expected) {
+		t.Errorf("Expected %d variables, got %d", len(expected), len(vars))
+	}
+	for name, value := range expected {
+		if vars[name] != value {
+			t.Errorf("Expected %s to be %q, got %q", name, value, vars[name])
+		}
+	}
+
+	for _, in := range []string{"NOVALUE", "1BAD=x", "QUOTE='open", `QUOTE="open`} {
+		if _, err := ParseDotEnv(strings.NewReader(in)); err == nil {
+			t.Errorf("Expected error parsing %q", in)
+		}
+	}
+}
+
+func TestApplyEnvironmentVariables(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/envvars": {http.StatusOK, []*EnvironmentVariable{
+			{ID: "1", Name: "KEEP", Value: "same"},
+			{ID: "2", Name: "CHANGE", Value: "old-secret"},
+			{ID: "3", Name: "REMOVE", Value: "gone"},
+		}},
+		"POST /naut/project/one/environment/prod/envvars":          {http.StatusCreated, nil},
+		"DELETE /naut/project/one/environment/prod/envvars/REMOVE": {http.StatusNoContent, nil},
+	})
+	defer ts.Close()
+
+	diff, err := api.PlanEnvironmentVariables("one", "prod", map[string]string{
+		"KEEP":   "same",
+		"CHANGE": "new-secret",
+		"ADD":    "added-secret",
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if diff.String() != "+ ADD=****\n~ CHANGE=****\n- REMOVE" {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+
+	if err := api.ApplyEnvironmentVariables("one", "prod", diff); err != nil {
+		t.Fatalf("%s", err)
+	}
+	sets := m.requestsTo("POST", "/naut/project/one/environment/prod/envvars")
+	if len(sets) != 2 || string(sets[0].body) != `{"name":"ADD","value":"added-secret"}` || string(sets[1].body) != `{"name":"CHANGE","value":"new-secret"}` {
+		t.Errorf("Unexpected updates %v", sets)
+	}
+	if len(m.requestsTo("DELETE", "/naut/project/one/environment/prod/envvars/REMOVE")) != 1 {
+		t.Error("Expected REMOVE to be deleted")
+	}
+
+	if diff := DiffEnvironmentVariables([]*EnvironmentVariable{{Name: "KEEP", Value: "same"}}, map[string]string{"KEEP": "same"}); !diff.Empty() {
+		t.Errorf("Expected empty diff, got %s", diff)
+	}
+}
+
+func TestEnvironmentVariableMasking(t *testing.T) {
+	v := &EnvironmentVariable{Name: "DB_PASSWORD", Value: "hunter2"}
+	c := &EnvironmentVariableChange{Name: "DB_PASSWORD", Old: "hunter2", New: "hunter3"}
+	for _, format := range []string{"%s", "%v", "%+v", "%#v"} {
+		for _, arg := range []interface{}{v, c, *v, *c, []*EnvironmentVariable{v}, []EnvironmentVariable{*v}} {
+			if out := fmt.Sprintf(format, arg); strings.Contains(out, "hunter") {
+				t.Errorf("Value leaked with %s: %s", format, out)
+			}
+		}
+	}
+
+	dump := maskSecrets([]byte(`{"name":"DB_PASSWORD","value":"hunter\"2"} {"attributes":{"value" : "x"}}`))
+	if string(dump) != `{"name":"DB_PASSWORD","value":"****"} {"attributes":{"value" : "****"}}` {
+		t.Errorf("Unexpected masked dump %s", dump)
+	}
+}
+
+func TestEnvironmentVariableDebugMasking(t *testing.T) {
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/one/environment/prod/envvars": {http.StatusCreated, &EnvironmentVariable{ID: "1", Name: "TOKEN", Value: "hunter2"}},
+	})
+	defer ts.Close()
+
+	os.Setenv("DEBUG", "1")
+	defer os.Unsetenv("DEBUG")
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := api.SetEnvironmentVariable("one", "prod", &SetEnvironmentVariable{Name: "TOKEN", Value: "hunter2"})
+	w.Close()
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	out, _ := ioutil.ReadAll(r)
+	if !strings.Contains(string(out), "TOKEN") || strings.Contains(string(out), "hunter2") {
+		t.Errorf("Expected masked debug output, got:\n%s", out)
+	}
+}
@@ -0,0 +1,259 @@
+package ssp
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportFormat string
+
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportJSON   = "json"
+)
+
+// ExportColumns are the CSV columns of a deployment export, in order.
+var ExportColumns = []string{
+	"stack", "environment", "id", "title", "sha", "ref", "state", "deployer", "requested", "started", "finished", "changes",
+}
+
+// ExportDeployments selects the deployments to export. Environments lists individual environments, and Stacks
+// adds all environments of the given stacks. If both are empty, all visible environments are exported.
+// An environment selected more than once is exported once. From and To limit the export by the deployment start
+// date, and are ignored if zero.
+type ExportDeployments struct {
+	Stacks       []string
+	Environments []EnvironmentRef
+	From         time.Time
+	To           time.Time
+	Format       ExportFormat
+}
+
+// DeploymentRecord is a single row of a deployment export. Times are RFC 3339 in UTC, and empty if unknown.
+// Finished is only set for deployments in a final state.
+type DeploymentRecord struct {
+	Stack       string                      `json:"stack"`
+	Environment string                      `json:"environment"`
+	ID          int                         `json:"id"`
+	Title       string                      `json:"title"`
+	SHA         string                      `json:"sha"`
+	Ref         string                      `json:"ref"`
+	State       State                       `json:"state"`
+	Deployer    string                      `json:"deployer"`
+	Requested   string                      `json:"requested"`
+	Started     string                      `json:"started"`
+	Finished    string                      `json:"finished"`
+	Changes     map[string]DeploymentChange `json:"changes"`
+}
+
+// NewDeploymentRecord flattens a deployment of the given environment into an export record.
+func NewDeploymentRecord(env EnvironmentRef, d *Deployment) *DeploymentRecord {
+	r := &DeploymentRecord{
+		Stack:       env.Stack,
+		Environment: env.Environment,
+		ID:          d.ID,
+		Title:       d.Title,
+		SHA:         d.SHA,
+		Ref:         d.RefName,
+		State:       d.State,
+		Deployer:    d.DeployerEmail,
+		Requested:   exportTime(d.DateRequested),
+		Started:     exportTime(d.DateStarted),
+		Changes:     d.Changes,
+	}
+	if d.State.Final() {
+		r.Finished = exportTime(d.DateUpdated)
+	}
+	if r.Changes == nil {
+		r.Changes = map[string]DeploymentChange{}
+	}
+	return r
+}
+
+// ExportDeployments writes the selected deployments to w in the requested format, one environment at a time,
+// and returns the number of deployments written.
+func (a *Client) ExportDeployments(w io.Writer, ed *ExportDeployments) (int, error) {
+	if ed == nil {
+		return 0, fmt.Errorf("export options are required")
+	}
+
+	ew, err := newExportWriter(w, ed.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	envs, err := a.exportEnvironments(ed)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := &DeploymentFilter{
+		DateStartedFrom: ed.From,
+		DateStartedTo:   ed.To,
+		Sort:            SortDateStarted,
+	}
+	count := 0
+	for _, env := range envs {
+		deployments, err := a.ListDeployments(env.Stack, env.Environment, filter)
+		if err != nil {
+			return count, fmt.Errorf("failed listing deployments of %s: '%s'", env, err)
+		}
+
+		for _, d := range deployments {
+			if err := ew.write(NewDeploymentRecord(env, d)); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, ew.close()
+}
+
+func (a *Client) exportEnvironments(ed *ExportDeployments) ([]EnvironmentRef, error) {
+	envs := []EnvironmentRef{}
+	seen := map[EnvironmentRef]bool{}
+	add := func(env EnvironmentRef) {
+		if !seen[env] {
+			seen[env] = true
+			envs = append(envs, env)
+		}
+	}
+
+	for _, env := range ed.Environments {
+		add(env)
+	}
+	if len(ed.Environments) > 0 && len(ed.Stacks) == 0 {
+		return envs, nil
+	}
+
+	stacks, err := a.ListStacks()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, s := range ed.Stacks {
+		wanted[s] = true
+	}
+	all := len(ed.Environments) == 0 && len(ed.Stacks) == 0
+
+	for _, s := range stacks {
+		if !all && !wanted[s.ID] {
+			continue
+		}
+		for _, e := range s.Environments {
+			add(EnvironmentRef{Stack: s.ID, Environment: e.ID})
+		}
+	}
+
+	return envs, nil
+}
+
+type exportWriter interface {
+	write(r *DeploymentRecord) error
+	close() error
+}
+
+func newExportWriter(w io.Writer, format ExportFormat) (exportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case ExportNDJSON:
+		return &jsonExportWriter{w: w, enc: json.NewEncoder(w)}, nil
+	case ExportJSON:
+		return &jsonExportWriter{w: w, enc: json.NewEncoder(w), array: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported export format '%s'", format)
+}
+
+type csvExportWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvExportWriter) write(r *DeploymentRecord) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(r.Changes))
+	for name := range r.Changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	changes := make([]string, len(names))
+	for i, name := range names {
+		changes[i] = fmt.Sprintf("%s: %s -> %s", name, r.Changes[name].From, r.Changes[name].To)
+	}
+
+	return c.w.Write([]string{
+		r.Stack, r.Environment, strconv.Itoa(r.ID), r.Title, r.SHA, r.Ref, string(r.State), r.Deployer,
+		r.Requested, r.Started, r.Finished, strings.Join(changes, "; "),
+	})
+}
+
+func (c *csvExportWriter) header() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(ExportColumns)
+}
+
+func (c *csvExportWriter) close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonExportWriter struct {
+	w       io.Writer
+	enc     *json.Encoder
+	array   bool
+	written int
+}
+
+func (j *jsonExportWriter) write(r *DeploymentRecord) error {
+	if j.array {
+		sep := ","
+		if j.written == 0 {
+			sep = "["
+		}
+		if _, err := io.WriteString(j.w, sep); err != nil {
+			return err
+		}
+	}
+	j.written++
+
+	// Encode terminates each record with a newline, which makes the NDJSON output.
+	return j.enc.Encode(r)
+}
+
+func (j *jsonExportWriter) close() error {
+	if !j.array {
+		return nil
+	}
+	end := "]\n"
+	if j.written == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
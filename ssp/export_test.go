@@ -0,0 +1,185 @@
+package ssp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportRoutes() map[string]mockRoute {
+	started := time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC)
+	changes := map[string]interface{}{
+		"Code version": &DeploymentChange{From: "abc", To: "def"},
+	}
+	return map[string]mockRoute{
+		"GET /naut/projects": {http.StatusOK, []*Stack{
+			{ID: "one", Environments: []*Environment{{ID: "prod"}}},
+			{ID: "two", Environments: []*Environment{{ID: "uat"}}},
+		}},
+		"GET /naut/project/one/environment/prod/deploys": {http.StatusOK, []*Deployment{
+			{
+				ID:              1,
+				Title:           "Release, with comma",
+				SHA:             "abcdef",
+				RefName:         "master",
+				OriginalState:   "Completed",
+				DeployerEmail:   "roger@over.nz",
+				DateRequested:   started.Add(-time.Hour),
+				DateStarted:     started,
+				DateUpdated:     started.Add(time.Minute),
+				OriginalChanges: changes,
+			},
+			{ID: 2, OriginalState: "Deploying", DateStarted: started.Add(time.Hour)},
+		}},
+		"GET /naut/project/two/environment/uat/deploys": {http.StatusOK, []*Deployment{}},
+	}
+}
+
+func TestExportDeploymentsCSV(t *testing.T) {
+	api, ts, m := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	out := &bytes.Buffer{}
+	n, err := api.ExportDeployments(out, &ExportDeployments{Stacks: []string{"one"}, Format: ExportCSV})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 deployments exported, got %d", n)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if strings.Join(rows[0], ",") != strings.Join(ExportColumns, ",") {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+	expected := []string{
+		"one", "prod", "1", "Release, with comma", "abcdef", "master", "Completed", "roger@over.nz",
+		"2017-03-01T09:00:00Z", "2017-03-01T10:00:00Z", "2017-03-01T10:01:00Z", "Code version: abc -> def",
+	}
+	if strings.Join(rows[1], "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected row\n%v\nexpected\n%v", rows[1], expected)
+	}
+	if rows[2][10] != "" {
+		t.Error("Deployment in progress should not have a finish time")
+	}
+	if len(m.requestsTo("GET", "/naut/project/two/environment/uat/deploys")) != 0 {
+		t.Error("Stack not selected for export was listed")
+	}
+}
+
+func TestExportDeploymentsJSON(t *testing.T) {
+	api, ts, _ := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	out := &bytes.Buffer{}
+	_, err := api.ExportDeployments(out, &ExportDeployments{Format: ExportJSON})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	records := []*DeploymentRecord{}
+	if err := json.Unmarshal(out.Bytes(), &records); err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+	if len(records) != 2 || records[0].Changes["Code version"].To != "def" {
+		t.Errorf("Unexpected records %s", out)
+	}
+}
+
+func TestExportDeploymentsNDJSON(t *testing.T) {
+	api, ts, _ := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	out := &bytes.Buffer{}
+	_, err := api.ExportDeployments(out, &ExportDeployments{
+		Environments: []EnvironmentRef{{Stack: "one", Environment: "prod"}},
+		Format:       ExportNDJSON,
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if strings.Count(out.String(), "\n") != 2 {
+		t.Errorf("Expected one record per line, got '%s'", out)
+	}
+	dec := json.NewDecoder(out)
+	count := 0
+	for dec.More() {
+		r := &DeploymentRecord{}
+		if err := dec.Decode(r); err != nil {
+			t.Fatalf("%s", err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 records, got %d", count)
+	}
+}
+
+func TestExportDeploymentsEmptyJSON(t *testing.T) {
+	api, ts, _ := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	out := &bytes.Buffer{}
+	_, err := api.ExportDeployments(out, &ExportDeployments{
+		Environments: []EnvironmentRef{{Stack: "two", Environment: "uat"}},
+		Format:       ExportJSON,
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if out.String() != "[]\n" {
+		t.Errorf("Expected empty array, got '%s'", out)
+	}
+}
+
+func TestExportDeploymentsUnknownFormat(t *testing.T) {
+	api, ts, _ := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	_, err := api.ExportDeployments(&bytes.Buffer{}, &ExportDeployments{Format: "xml"})
+	if err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestExportDeploymentsNilOptions(t *testing.T) {
+	api, ts, _ := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	_, err := api.ExportDeployments(&bytes.Buffer{}, nil)
+	if err == nil {
+		t.Error("Expected error without options")
+	}
+}
+
+func TestExportDeploymentsOverlapping(t *testing.T) {
+	api, ts, m := newMockRouter(exportRoutes())
+	defer ts.Close()
+
+	out := &bytes.Buffer{}
+	count, err := api.ExportDeployments(out, &ExportDeployments{
+		Stacks: []string{"one"},
+		Environments: []EnvironmentRef{
+			{Stack: "one", Environment: "prod"},
+			{Stack: "one", Environment: "prod"},
+		},
+		Format: ExportNDJSON,
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 records, got %d", count)
+	}
+	if n := len(m.requestsTo("GET", "/naut/project/one/environment/prod/deploys")); n != 1 {
+		t.Errorf("Expected the environment to be listed once, got %d", n)
+	}
+}
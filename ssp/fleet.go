@@ -0,0 +1,217 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrFleetAborted is returned when a fleet rollout stops early because too many targets failed.
+var ErrFleetAborted = errors.New("fleet rollout aborted, failure threshold exceeded")
+
+// FleetTarget identifies a single environment taking part in a fleet rollout. It is an alias of EnvironmentRef,
+// kept for existing callers.
+type FleetTarget = EnvironmentRef
+
+// FleetRollout configures a rollout of one ref across many environments, in waves.
+//
+// Deployment is used as a template for every target, so it should normally have BypassAndStart set - otherwise
+// the rollout waits for each deployment to be approved and started elsewhere.
+//
+// WaveSizes lists the number of targets in each successive wave. The last size is reused until all targets are
+// covered, and no sizes means a single wave. Concurrency limits the number of deployments running at the same
+// time within a wave, zero meaning the whole wave at once.
+//
+// Deployments are polled every PollInterval, DefaultPollInterval if zero. If Check is set, it runs on every
+// completed deployment, and an unhealthy target counts as failed.
+//
+// Once more than MaxFailures targets have failed, the rollout stops before the next wave. If OnThresholdExceeded
+// is set, the rollout pauses until it returns, and continues if it returns true.
+type FleetRollout struct {
+	Targets             []EnvironmentRef
+	Deployment          CreateDeployment
+	WaveSizes           []int
+	Concurrency         int
+	MaxFailures         int
+	PollInterval        time.Duration
+	Check               DeploymentCheck
+	OnThresholdExceeded func(report *FleetReport) bool
+}
+
+// FleetTargetResult is the outcome of the rollout on a single target. Skipped targets were never deployed
+// because the rollout was aborted.
+type FleetTargetResult struct {
+	Target     EnvironmentRef
+	Wave       int
+	Deployment *Deployment
+	Health     *HealthReport
+	Skipped    bool
+	Err        error
+	Started    time.Time
+	Finished   time.Time
+}
+
+// Failed reports whether the target could not be deployed, its deployment did not complete, or it failed the
+// health check.
+func (r *FleetTargetResult) Failed() bool {
+	if r.Skipped {
+		return false
+	}
+	if r.Health != nil && !r.Health.Healthy {
+		return true
+	}
+	return r.Err != nil || r.Deployment == nil || r.Deployment.State != StateCompleted
+}
+
+// FleetReport aggregates per-target results of a fleet rollout, in the order the targets were given.
+type FleetReport struct {
+	Results   []*FleetTargetResult
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Aborted   bool
+}
+
+// RolloutFleet deploys the same ref to all targets, wave by wave. The returned report is always populated,
+// including when the rollout is aborted or the context is cancelled.
+func (a *Client) RolloutFleet(ctx context.Context, fr *FleetRollout) (*FleetReport, error) {
+	if fr == nil || len(fr.Targets) == 0 {
+		return nil, errors.New("no fleet targets given")
+	}
+	if fr.Deployment.Ref == "" {
+		return nil, errors.New("fleet deployment ref is required")
+	}
+	if err := ValidateDeploymentOptions(fr.Deployment.Options); err != nil {
+		return nil, err
+	}
+	for _, size := range fr.WaveSizes {
+		if size < 1 {
+			return nil, fmt.Errorf("invalid wave size %d", size)
+		}
+	}
+
+	interval := fr.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	report := &FleetReport{
+		Results: make([]*FleetTargetResult, len(fr.Targets)),
+	}
+	for i, t := range fr.Targets {
+		report.Results[i] = &FleetTargetResult{Target: t, Skipped: true}
+	}
+
+	var err error
+	for wave, waveStart := 0, 0; waveStart < len(fr.Targets); wave++ {
+		size := len(fr.Targets)
+		if len(fr.WaveSizes) > 0 {
+			size = fr.WaveSizes[len(fr.WaveSizes)-1]
+			if wave < len(fr.WaveSizes) {
+				size = fr.WaveSizes[wave]
+			}
+		}
+		waveEnd := waveStart + size
+		if waveEnd > len(fr.Targets) {
+			waveEnd = len(fr.Targets)
+		}
+
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		a.rolloutWave(ctx, fr, report.Results[waveStart:waveEnd], wave+1, interval)
+		report.tally()
+
+		waveStart = waveEnd
+		if report.Failed > fr.MaxFailures && waveStart < len(fr.Targets) {
+			if fr.OnThresholdExceeded == nil || !fr.OnThresholdExceeded(report) {
+				report.Aborted = true
+				err = ErrFleetAborted
+				break
+			}
+		}
+	}
+
+	report.tally()
+	if err == nil && report.Skipped > 0 {
+		err = ctx.Err()
+	}
+	return report, err
+}
+
+func (a *Client) rolloutWave(ctx context.Context, fr *FleetRollout, results []*FleetTargetResult, wave int, interval time.Duration) {
+	concurrency := fr.Concurrency
+	if concurrency <= 0 || concurrency > len(results) {
+		concurrency = len(results)
+	}
+
+	sem := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	for _, r := range results {
+		// Targets not started before the context is done stay skipped.
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(r *FleetTargetResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			a.rolloutTarget(ctx, fr, r, wave, interval)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (a *Client) rolloutTarget(ctx context.Context, fr *FleetRollout, r *FleetTargetResult, wave int, interval time.Duration) {
+	r.Wave = wave
+	r.Skipped = false
+	r.Started = time.Now()
+	defer func() {
+		r.Finished = time.Now()
+	}()
+
+	cd := fr.Deployment
+	d, err := a.CreateDeployment(r.Target.Stack, r.Target.Environment, &cd)
+	if err != nil {
+		r.Err = fmt.Errorf("failed creating deployment on %s: '%s'", r.Target, err)
+		return
+	}
+	r.Deployment = d
+
+	d, err = a.WaitForDeployment(ctx, r.Target.Stack, r.Target.Environment, d.ID, interval)
+	if d != nil {
+		r.Deployment = d
+	}
+	if err != nil {
+		r.Err = fmt.Errorf("failed waiting for deployment on %s: '%s'", r.Target, err)
+		return
+	}
+
+	if fr.Check != nil && d.State == StateCompleted {
+		r.Health = fr.Check.CheckDeployment(ctx, r.Target, d)
+	}
+}
+
+func (r *FleetReport) tally() {
+	r.Succeeded, r.Failed, r.Skipped = 0, 0, 0
+	for _, res := range r.Results {
+		switch {
+		case res.Skipped:
+			r.Skipped++
+		case res.Failed():
+			r.Failed++
+		default:
+			r.Succeeded++
+		}
+	}
+}
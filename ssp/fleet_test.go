@@ -0,0 +1,148 @@
+package ssp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func fleetRoutes(states map[string]string) map[string]mockRoute {
+	routes := map[string]mockRoute{}
+	for stack, state := range states {
+		routes["POST /naut/project/"+stack+"/environment/prod/deploys"] = mockRoute{http.StatusCreated, &Deployment{ID: 1, OriginalState: "Queued"}}
+		routes["GET /naut/project/"+stack+"/environment/prod/deploys/1"] = mockRoute{http.StatusOK, &Deployment{ID: 1, OriginalState: state}}
+	}
+	return routes
+}
+
+func fleetTargets(stacks ...string) []EnvironmentRef {
+	targets := make([]EnvironmentRef, len(stacks))
+	for i, s := range stacks {
+		targets[i] = EnvironmentRef{Stack: s, Environment: "prod"}
+	}
+	return targets
+}
+
+func TestRolloutFleet(t *testing.T) {
+	api, ts, _ := newMockRouter(fleetRoutes(map[string]string{
+		"a": "Completed",
+		"b": "Completed",
+		"c": "Completed",
+	}))
+	defer ts.Close()
+
+	report, err := api.RolloutFleet(context.Background(), &FleetRollout{
+		Targets:      fleetTargets("a", "b", "c"),
+		Deployment:   CreateDeployment{Ref: "master", RefType: RefTypeBranch, BypassAndStart: true},
+		WaveSizes:    []int{1, 1},
+		Concurrency:  1,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if report.Succeeded != 3 || report.Failed != 0 || report.Skipped != 0 {
+		t.Errorf("Unexpected report totals: %+v", report)
+	}
+	for i, r := range report.Results {
+		if r.Wave != i+1 {
+			t.Errorf("Target %s deployed in wave %d, expected %d", r.Target, r.Wave, i+1)
+		}
+	}
+}
+
+func TestRolloutFleetAbort(t *testing.T) {
+	api, ts, m := newMockRouter(fleetRoutes(map[string]string{
+		"a": "Completed",
+		"b": "Failed",
+		"c": "Completed",
+		"d": "Completed",
+	}))
+	defer ts.Close()
+
+	report, err := api.RolloutFleet(context.Background(), &FleetRollout{
+		Targets:      fleetTargets("a", "b", "c", "d"),
+		Deployment:   CreateDeployment{Ref: "master", RefType: RefTypeBranch, BypassAndStart: true},
+		WaveSizes:    []int{1, 2},
+		PollInterval: time.Millisecond,
+	})
+	if err != ErrFleetAborted {
+		t.Fatalf("Expected ErrFleetAborted, got %v", err)
+	}
+	if !report.Aborted || report.Succeeded != 2 || report.Failed != 1 || report.Skipped != 1 {
+		t.Errorf("Unexpected report totals: %+v", report)
+	}
+	if !report.Results[3].Skipped {
+		t.Error("Last target should have been skipped")
+	}
+	if len(m.requestsTo("POST", "/naut/project/d/environment/prod/deploys")) != 0 {
+		t.Error("Skipped target should not be deployed")
+	}
+}
+
+func TestRolloutFleetContinueAfterThreshold(t *testing.T) {
+	api, ts, _ := newMockRouter(fleetRoutes(map[string]string{
+		"a": "Failed",
+		"b": "Completed",
+	}))
+	defer ts.Close()
+
+	paused := 0
+	report, err := api.RolloutFleet(context.Background(), &FleetRollout{
+		Targets:      fleetTargets("a", "b"),
+		Deployment:   CreateDeployment{Ref: "master", RefType: RefTypeBranch, BypassAndStart: true},
+		WaveSizes:    []int{1},
+		PollInterval: time.Millisecond,
+		OnThresholdExceeded: func(r *FleetReport) bool {
+			paused++
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if paused != 1 {
+		t.Errorf("Expected the rollout to pause once, paused %d times", paused)
+	}
+	if report.Succeeded != 1 || report.Failed != 1 {
+		t.Errorf("Unexpected report totals: %+v", report)
+	}
+}
+
+func TestRolloutFleetCancelled(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/a/environment/prod/deploys":  {http.StatusCreated, &Deployment{ID: 1, OriginalState: "Queued"}},
+		"GET /naut/project/a/environment/prod/deploys/1": {http.StatusOK, &Deployment{ID: 1, OriginalState: "Deploying"}},
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	report, err := api.RolloutFleet(ctx, &FleetRollout{
+		Targets:      fleetTargets("a", "b", "c"),
+		Deployment:   CreateDeployment{Ref: "master", RefType: RefTypeBranch, BypassAndStart: true},
+		Concurrency:  1,
+		MaxFailures:  5,
+		PollInterval: time.Millisecond,
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if report.Failed != 1 || report.Skipped != 2 {
+		t.Errorf("Expected targets after cancellation to be skipped, got %+v", report)
+	}
+	for _, s := range []string{"b", "c"} {
+		if len(m.requestsTo("POST", "/naut/project/"+s+"/environment/prod/deploys")) != 0 {
+			t.Errorf("Target %s should not be deployed after cancellation", s)
+		}
+	}
+}
+
+func TestFleetTargetAlias(t *testing.T) {
+	var target FleetTarget = EnvironmentRef{Stack: "one", Environment: "prod"}
+	if target.String() != "one/prod" {
+		t.Errorf("Unexpected target %s", target)
+	}
+}
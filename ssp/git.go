@@ -0,0 +1,350 @@
+package ssp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDirtyWorkingCopy is returned when deploying from a working copy with uncommitted changes.
+var ErrDirtyWorkingCopy = errors.New("working copy has uncommitted changes")
+
+// UnpushedError is returned when deploying a commit that the remote does not have. Reason explains what is
+// missing from the remote.
+type UnpushedError struct {
+	Reason string
+}
+
+func (e *UnpushedError) Error() string {
+	return fmt.Sprintf("HEAD has not been pushed: %s", e.Reason)
+}
+
+// Commit is a single commit read from a local git repository. PullRequest is the number of the pull request the
+// commit merged, or zero.
+type Commit struct {
+	SHA         string
+	Author      string
+	AuthorEmail string
+	Date        time.Time
+	Subject     string
+	Merge       bool
+	PullRequest int
+}
+
+// Changelog lists the commits a deployment adds on top of what is live. Removed lists commits that are live but
+// not part of the deployment, which is the case for rollbacks.
+type Changelog struct {
+	From    string
+	To      string
+	Added   []*Commit
+	Removed []*Commit
+}
+
+// gitSHAPattern matches abbreviated and full commit SHAs. Anything else is refused, so a value like "--output"
+// cannot be passed to git as an option.
+var gitSHAPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+var pullRequestPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^Merge pull request #(\d+)`),
+	regexp.MustCompile(`\(#(\d+)\)$`),
+}
+
+// GitHead describes the checked out commit of a local working copy. Tag and Branch are empty if HEAD is not
+// tagged, or is detached.
+type GitHead struct {
+	SHA     string
+	Tag     string
+	Branch  string
+	Subject string
+	Body    string
+}
+
+// ReadGitHead inspects the working copy at repoPath, and verifies it is clean and its HEAD has been pushed to the
+// remote, "origin" if empty. A tagged HEAD must have the same tag on the remote, a branch must not be ahead of the
+// branch of the same name on the remote, and a detached HEAD must be contained in one of the remote's branches.
+// Branches are compared against the remote-tracking refs/remotes/<remote>/ refs, and nothing is fetched, so fetch
+// first if those may be out of date.
+func ReadGitHead(repoPath string, remote string) (*GitHead, error) {
+	if remote == "" {
+		remote = "origin"
+	}
+	if strings.HasPrefix(remote, "-") {
+		return nil, fmt.Errorf("invalid remote '%s'", remote)
+	}
+
+	status, err := git(repoPath, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(status) != "" {
+		return nil, ErrDirtyWorkingCopy
+	}
+
+	out, err := git(repoPath, "log", "-1", "--format=%H%x1f%s%x1f%b")
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.SplitN(out, "\x1f", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected git log output '%s'", out)
+	}
+	h := &GitHead{
+		SHA:     fields[0],
+		Subject: fields[1],
+		Body:    strings.TrimSpace(fields[2]),
+	}
+
+	tags, err := git(repoPath, "tag", "--points-at", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if t := strings.Fields(tags); len(t) > 0 {
+		h.Tag = t[0]
+	}
+	if branch, err := git(repoPath, "symbolic-ref", "-q", "--short", "HEAD"); err == nil {
+		h.Branch = strings.TrimSpace(branch)
+	}
+
+	if err := h.checkPushed(repoPath, remote); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// CreateDeployment builds a deployment of the working copy HEAD. Tags are deployed by name, while branches and
+// detached HEADs are deployed by their exact SHA, so commits pushed in the meantime are not picked up.
+func (h *GitHead) CreateDeployment() *CreateDeployment {
+	cd := &CreateDeployment{
+		Ref:     h.SHA,
+		RefType: RefTypeSHA,
+		Title:   h.Subject,
+	}
+	if h.Tag != "" {
+		cd.Ref = h.Tag
+		cd.RefType = RefTypeTag
+	}
+
+	source := "detached HEAD"
+	switch {
+	case h.Tag != "":
+		source = fmt.Sprintf("tag %s", h.Tag)
+	case h.Branch != "":
+		source = fmt.Sprintf("branch %s", h.Branch)
+	}
+	cd.Summary = fmt.Sprintf("Deployed %s from %s.", h.SHA, source)
+	if h.Body != "" {
+		cd.Summary = fmt.Sprintf("%s\n\n%s", h.Body, cd.Summary)
+	}
+
+	return cd
+}
+
+func (h *GitHead) checkPushed(repoPath string, remote string) error {
+	if h.Tag != "" {
+		out, err := git(repoPath, "ls-remote", "--tags", remote, fmt.Sprintf("refs/tags/%s", h.Tag), fmt.Sprintf("refs/tags/%s^{}", h.Tag))
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if f := strings.Fields(line); len(f) == 2 && f[0] == h.SHA {
+				return nil
+			}
+		}
+		return &UnpushedError{Reason: fmt.Sprintf("tag %s is not on %s", h.Tag, remote)}
+	}
+
+	if h.Branch != "" {
+		tracking := fmt.Sprintf("refs/remotes/%s/%s", remote, h.Branch)
+		if _, err := git(repoPath, "rev-parse", "-q", "--verify", tracking); err != nil {
+			return &UnpushedError{Reason: fmt.Sprintf("branch %s is not on %s", h.Branch, remote)}
+		}
+		ahead, err := git(repoPath, "rev-list", "--count", fmt.Sprintf("%s..HEAD", tracking))
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(ahead) != "0" {
+			return &UnpushedError{Reason: fmt.Sprintf("branch %s is %s commits ahead of %s", h.Branch, strings.TrimSpace(ahead), remote)}
+		}
+		return nil
+	}
+
+	branches, err := git(repoPath, "for-each-ref", "--format=%(refname)", "--contains", "HEAD", fmt.Sprintf("refs/remotes/%s/", remote))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(branches) == "" {
+		return &UnpushedError{Reason: fmt.Sprintf("%s is not on any branch of %s", h.SHA, remote)}
+	}
+	return nil
+}
+
+// DeploymentChangelog compares the SHA of the current deployment of the environment with the SHA of the given
+// deployment, using the history of the local git repository at repoPath.
+func (a *Client) DeploymentChangelog(sID string, eID string, dID string, repoPath string) (*Changelog, error) {
+	current, err := a.GetDeploymentCurrent(sID, eID)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching current deployment: '%s'", err)
+	}
+	pending, err := a.GetDeployment(sID, eID, dID)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching deployment: '%s'", err)
+	}
+
+	return GitChangelog(repoPath, current.SHA, pending.SHA)
+}
+
+// GitChangelog lists the commits between two SHAs in the local git repository at repoPath. Both must be
+// lowercase hex SHAs of 7 to 40 characters.
+func GitChangelog(repoPath string, from string, to string) (*Changelog, error) {
+	for _, sha := range []string{from, to} {
+		if !gitSHAPattern.MatchString(sha) {
+			return nil, fmt.Errorf("invalid SHA '%s'", sha)
+		}
+	}
+
+	added, err := gitLog(repoPath, fmt.Sprintf("%s..%s", from, to))
+	if err != nil {
+		return nil, err
+	}
+	removed, err := gitLog(repoPath, fmt.Sprintf("%s..%s", to, from))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Changelog{
+		From:    from,
+		To:      to,
+		Added:   added,
+		Removed: removed,
+	}, nil
+}
+
+// Text renders the changelog as plain text.
+func (c *Changelog) Text() string {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "Changes from %s to %s:\n", shortGitSHA(c.From), shortGitSHA(c.To))
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		fmt.Fprintf(b, "  No changes.\n")
+	}
+	for _, commit := range c.Added {
+		fmt.Fprintf(b, "  + %s %s (%s, %s)\n", shortGitSHA(commit.SHA), commit.Subject, commit.Author, commit.Date.Format("2006-01-02"))
+	}
+	for _, commit := range c.Removed {
+		fmt.Fprintf(b, "  - %s %s (%s, %s)\n", shortGitSHA(commit.SHA), commit.Subject, commit.Author, commit.Date.Format("2006-01-02"))
+	}
+	return b.String()
+}
+
+// Markdown renders the changelog as Markdown lists, suitable for a deployment summary.
+func (c *Changelog) Markdown() string {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "### Changes from `%s` to `%s`\n\n", shortGitSHA(c.From), shortGitSHA(c.To))
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		fmt.Fprintf(b, "No changes.\n")
+		return b.String()
+	}
+
+	if len(c.Added) > 0 {
+		fmt.Fprintf(b, "**Added**\n\n")
+		for _, commit := range c.Added {
+			fmt.Fprintf(b, "- %s\n", commit.markdown())
+		}
+	}
+	if len(c.Removed) > 0 {
+		if len(c.Added) > 0 {
+			fmt.Fprintf(b, "\n")
+		}
+		fmt.Fprintf(b, "**Removed**\n\n")
+		for _, commit := range c.Removed {
+			fmt.Fprintf(b, "- %s\n", commit.markdown())
+		}
+	}
+	return b.String()
+}
+
+// PullRequests returns the numbers of pull requests merged by the added commits.
+func (c *Changelog) PullRequests() []int {
+	prs := []int{}
+	for _, commit := range c.Added {
+		if commit.PullRequest != 0 {
+			prs = append(prs, commit.PullRequest)
+		}
+	}
+	return prs
+}
+
+func (c *Commit) markdown() string {
+	s := fmt.Sprintf("`%s` %s - %s, %s", shortGitSHA(c.SHA), markdownCell(c.Subject), markdownCell(c.Author), c.Date.Format("2006-01-02"))
+	if c.PullRequest != 0 && !strings.Contains(c.Subject, fmt.Sprintf("#%d", c.PullRequest)) {
+		s += fmt.Sprintf(" (#%d)", c.PullRequest)
+	}
+	return s
+}
+
+func gitLog(repoPath string, revRange string) ([]*Commit, error) {
+	out, err := git(repoPath, "log", "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%P%x1f%s%x1e", revRange)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []*Commit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		fields := strings.Split(record, "\x1f")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected git log output '%s'", record)
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing commit date: '%s'", err)
+		}
+
+		commit := &Commit{
+			SHA:         fields[0],
+			Author:      fields[1],
+			AuthorEmail: fields[2],
+			Date:        date,
+			Merge:       len(strings.Fields(fields[4])) > 1,
+			Subject:     fields[5],
+		}
+		for _, p := range pullRequestPatterns {
+			if m := p.FindStringSubmatch(commit.Subject); m != nil {
+				commit.PullRequest, _ = strconv.Atoi(m[1])
+				break
+			}
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// git runs a git command in the repository and returns its standard output.
+func git(repoPath string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: '%s'", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func shortGitSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,268 @@
+package ssp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitRepo creates a temporary git repository. Call the returned function to remove it.
+func newGitRepo(t *testing.T) (string, func()) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir, err := ioutil.TempDir("", "ssp-git")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "config", "user.name", "Roger")
+	runGit(t, dir, "config", "user.email", "roger@over.nz")
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2017-03-01T10:00:00Z", "GIT_COMMITTER_DATE=2017-03-01T10:00:00Z")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s", strings.Join(args, " "), out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func gitCommit(t *testing.T, dir string, file string, subject string) string {
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(subject), 0644); err != nil {
+		t.Fatalf("%s", err)
+	}
+	runGit(t, dir, "add", file)
+	runGit(t, dir, "commit", "-q", "-m", subject)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+func TestGitChangelog(t *testing.T) {
+	dir, cleanup := newGitRepo(t)
+	defer cleanup()
+
+	live := gitCommit(t, dir, "a.txt", "Initial")
+	gitCommit(t, dir, "b.txt", "Add feature (#12)")
+	pending := gitCommit(t, dir, "c.txt", "Fix bug")
+
+	c, err := GitChangelog(dir, live, pending)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(c.Added) != 2 || len(c.Removed) != 0 {
+		t.Fatalf("Expected 2 added commits, got %d added and %d removed", len(c.Added), len(c.Removed))
+	}
+	if c.Added[0].Subject != "Fix bug" || c.Added[0].Author != "Roger" {
+		t.Errorf("Unexpected commit %+v", c.Added[0])
+	}
+	if prs := c.PullRequests(); len(prs) != 1 || prs[0] != 12 {
+		t.Errorf("Unexpected pull requests %v", prs)
+	}
+	if !strings.Contains(c.Text(), "+ "+pending[:7]+" Fix bug (Roger, 2017-03-01)") {
+		t.Errorf("Unexpected text:\n%s", c.Text())
+	}
+	if !strings.Contains(c.Markdown(), "- `"+pending[:7]+"` Fix bug - Roger, 2017-03-01") {
+		t.Errorf("Unexpected markdown:\n%s", c.Markdown())
+	}
+
+	rollback, err := GitChangelog(dir, pending, live)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(rollback.Added) != 0 || len(rollback.Removed) != 2 {
+		t.Errorf("Expected 2 removed commits, got %d added and %d removed", len(rollback.Added), len(rollback.Removed))
+	}
+}
+
+func TestGitChangelogUnknownSHA(t *testing.T) {
+	dir, cleanup := newGitRepo(t)
+	defer cleanup()
+
+	live := gitCommit(t, dir, "a.txt", "Initial")
+	_, err := GitChangelog(dir, live, "0000000000000000000000000000000000000000")
+	if err == nil {
+		t.Error("Expected error for unknown SHA")
+	}
+}
+
+func TestGitChangelogInvalidSHA(t *testing.T) {
+	dir, cleanup := newGitRepo(t)
+	defer cleanup()
+
+	live := gitCommit(t, dir, "a.txt", "Initial")
+	for _, sha := range []string{"", "--output=/tmp/x", "HEAD", "ABCDEF0", "abc"} {
+		if _, err := GitChangelog(dir, live, sha); err == nil {
+			t.Errorf("Expected error for SHA '%s'", sha)
+		}
+		if _, err := GitChangelog(dir, sha, live); err == nil {
+			t.Errorf("Expected error for SHA '%s'", sha)
+		}
+	}
+}
+
+func TestChangelogMarkdownEscaping(t *testing.T) {
+	c := &Changelog{
+		From:  "aaaaaaa",
+		To:    "bbbbbbb",
+		Added: []*Commit{{SHA: "bbbbbbb", Subject: "Use `a|b` as separator", Author: "Roger"}},
+	}
+	if !strings.Contains(c.Markdown(), "- `bbbbbbb` Use \\`a\\|b\\` as separator - Roger") {
+		t.Errorf("Unexpected markdown:\n%s", c.Markdown())
+	}
+}
+
+func TestDeploymentChangelog(t *testing.T) {
+	dir, cleanup := newGitRepo(t)
+	defer cleanup()
+
+	live := gitCommit(t, dir, "a.txt", "Initial")
+	pending := gitCommit(t, dir, "b.txt", "Add feature")
+
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/deploys/current": {http.StatusOK, &Deployment{ID: 1, SHA: live}},
+		"GET /naut/project/one/environment/prod/deploys/2":       {http.StatusOK, &Deployment{ID: 2, SHA: pending}},
+	})
+	defer ts.Close()
+
+	c, err := api.DeploymentChangelog("one", "prod", "2", dir)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(c.Added) != 1 || c.Added[0].SHA != pending {
+		t.Errorf("Unexpected changelog %+v", c)
+	}
+}
+
+func isUnpushed(err error) bool {
+	_, ok := err.(*UnpushedError)
+	return ok
+}
+
+// newGitClone creates a temporary bare repository with a clone of it, with one pushed commit on master.
+func newGitClone(t *testing.T) (string, func()) {
+	remote, cleanupRemote := newGitRepo(t)
+	runGit(t, remote, "config", "core.bare", "true")
+
+	dir, cleanup := newGitRepo(t)
+	runGit(t, dir, "remote", "add", "origin", remote)
+	runGit(t, dir, "checkout", "-q", "-b", "master")
+	gitCommit(t, dir, "a.txt", "Initial")
+	runGit(t, dir, "push", "-q", "-u", "origin", "master")
+
+	return dir, func() {
+		cleanup()
+		cleanupRemote()
+	}
+}
+
+func TestReadGitHeadBranch(t *testing.T) {
+	dir, cleanup := newGitClone(t)
+	defer cleanup()
+
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "Release\n\nWith a longer description.")
+	runGit(t, dir, "push", "-q")
+	sha := runGit(t, dir, "rev-parse", "HEAD")
+
+	h, err := ReadGitHead(dir, "")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if h.Branch != "master" || h.Tag != "" || h.SHA != sha {
+		t.Errorf("Unexpected head %+v", h)
+	}
+
+	cd := h.CreateDeployment()
+	if cd.RefType != RefTypeSHA || cd.Ref != sha {
+		t.Errorf("Branch should be deployed by SHA, got '%s' of type '%s'", cd.Ref, cd.RefType)
+	}
+	if cd.Title != "Release" || !strings.HasPrefix(cd.Summary, "With a longer description.") {
+		t.Errorf("Unexpected title '%s' and summary '%s'", cd.Title, cd.Summary)
+	}
+}
+
+func TestReadGitHeadTag(t *testing.T) {
+	dir, cleanup := newGitClone(t)
+	defer cleanup()
+
+	runGit(t, dir, "tag", "-a", "v1.0.0", "-m", "Version 1")
+	if _, err := ReadGitHead(dir, "origin"); !isUnpushed(err) {
+		t.Errorf("Expected unpushed tag error, got %v", err)
+	}
+
+	runGit(t, dir, "push", "-q", "origin", "v1.0.0")
+	h, err := ReadGitHead(dir, "origin")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	cd := h.CreateDeployment()
+	if cd.RefType != RefTypeTag || cd.Ref != "v1.0.0" {
+		t.Errorf("Unexpected ref '%s' of type '%s'", cd.Ref, cd.RefType)
+	}
+}
+
+func TestReadGitHeadDetached(t *testing.T) {
+	dir, cleanup := newGitClone(t)
+	defer cleanup()
+
+	runGit(t, dir, "checkout", "-q", "--detach")
+	if _, err := ReadGitHead(dir, ""); err != nil {
+		t.Errorf("%s", err)
+	}
+
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "Local only")
+	if _, err := ReadGitHead(dir, ""); !isUnpushed(err) {
+		t.Errorf("Expected unpushed commit error, got %v", err)
+	}
+}
+
+func TestReadGitHeadRefusesUnsafeStates(t *testing.T) {
+	dir, cleanup := newGitClone(t)
+	defer cleanup()
+
+	gitCommit(t, dir, "b.txt", "Not pushed")
+	if _, err := ReadGitHead(dir, ""); !isUnpushed(err) {
+		t.Errorf("Expected unpushed branch error, got %v", err)
+	}
+
+	runGit(t, dir, "push", "-q")
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err := ReadGitHead(dir, ""); err != ErrDirtyWorkingCopy {
+		t.Errorf("Expected ErrDirtyWorkingCopy, got %v", err)
+	}
+}
+
+func TestReadGitHeadChecksGivenRemote(t *testing.T) {
+	dir, cleanup := newGitClone(t)
+	defer cleanup()
+
+	mirror, cleanupMirror := newGitRepo(t)
+	defer cleanupMirror()
+	runGit(t, mirror, "config", "core.bare", "true")
+	runGit(t, dir, "remote", "add", "mirror", mirror)
+
+	if _, err := ReadGitHead(dir, "mirror"); !isUnpushed(err) {
+		t.Errorf("Expected unpushed branch error, got %v", err)
+	}
+	runGit(t, dir, "checkout", "-q", "--detach")
+	if _, err := ReadGitHead(dir, "mirror"); !isUnpushed(err) {
+		t.Errorf("Expected unpushed commit error, got %v", err)
+	}
+
+	runGit(t, dir, "push", "-q", "mirror", "master")
+	if _, err := ReadGitHead(dir, "mirror"); err != nil {
+		t.Errorf("%s", err)
+	}
+	if _, err := ReadGitHead(dir, "--upload-pack=x"); err == nil {
+		t.Error("Expected error for invalid remote")
+	}
+}
@@ -0,0 +1,263 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthCheck probes a single URL. The response must have one of ExpectedStatus (200 if empty), contain all of
+// BodyContains, and arrive within MaxLatency if set.
+type HealthCheck struct {
+	Name           string
+	URL            string
+	Method         string
+	ExpectedStatus []int
+	BodyContains   []string
+	MaxLatency     time.Duration
+}
+
+func (c *HealthCheck) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.URL
+}
+
+// HealthResult is the outcome of the last attempt of a check. Reason explains why an unhealthy check failed.
+type HealthResult struct {
+	Check    *HealthCheck
+	Healthy  bool
+	Status   int
+	Latency  time.Duration
+	Attempts int
+	Reason   string
+}
+
+// HealthReport is the outcome of all checks. It is healthy only if every check passed.
+type HealthReport struct {
+	Healthy bool
+	Results []*HealthResult
+}
+
+// Failures returns the results of the checks that did not pass.
+func (r *HealthReport) Failures() []*HealthResult {
+	failures := []*HealthResult{}
+	for _, res := range r.Results {
+		if !res.Healthy {
+			failures = append(failures, res)
+		}
+	}
+	return failures
+}
+
+func (r *HealthReport) String() string {
+	if r.Healthy {
+		return "healthy"
+	}
+	reasons := []string{}
+	for _, res := range r.Failures() {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", res.Check, res.Reason))
+	}
+	return fmt.Sprintf("unhealthy (%s)", strings.Join(reasons, "; "))
+}
+
+// HealthChecker runs a set of checks. A failing check is retried every Interval (5 seconds by default) until it
+// passes or GracePeriod has passed since the start, so a site can warm up after a deployment. Timeout limits each
+// request, 10 seconds by default. Client defaults to http.DefaultClient.
+type HealthChecker struct {
+	Checks      []*HealthCheck
+	GracePeriod time.Duration
+	Interval    time.Duration
+	Timeout     time.Duration
+	Client      *http.Client
+}
+
+// Run runs all checks concurrently and waits for them to pass or give up.
+func (hc *HealthChecker) Run(ctx context.Context) *HealthReport {
+	report := &HealthReport{
+		Healthy: true,
+		Results: make([]*HealthResult, len(hc.Checks)),
+	}
+	deadline := time.Now().Add(hc.GracePeriod)
+
+	wg := &sync.WaitGroup{}
+	for i, c := range hc.Checks {
+		wg.Add(1)
+		go func(i int, c *HealthCheck) {
+			defer wg.Done()
+			report.Results[i] = hc.retry(ctx, c, deadline)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, res := range report.Results {
+		if !res.Healthy {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// CheckDeployment runs the checks regardless of the target, which makes HealthChecker a DeploymentCheck.
+func (hc *HealthChecker) CheckDeployment(ctx context.Context, target EnvironmentRef, d *Deployment) *HealthReport {
+	return hc.Run(ctx)
+}
+
+func (hc *HealthChecker) retry(ctx context.Context, c *HealthCheck, deadline time.Time) *HealthResult {
+	interval := hc.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		res := hc.probe(ctx, c)
+		res.Attempts = attempt
+		if res.Healthy || !time.Now().Add(interval).Before(deadline) {
+			return res
+		}
+
+		select {
+		case <-ctx.Done():
+			res.Reason = fmt.Sprintf("%s, then %s", res.Reason, ctx.Err())
+			return res
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (hc *HealthChecker) probe(ctx context.Context, c *HealthCheck) *HealthResult {
+	res := &HealthResult{Check: c}
+
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := c.Method
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequest(method, c.URL, nil)
+	if err != nil {
+		res.Reason = err.Error()
+		return res
+	}
+	req = req.WithContext(ctx)
+
+	client := hc.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Reason = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	res.Latency = time.Since(start)
+	res.Status = resp.StatusCode
+	if err != nil {
+		res.Reason = err.Error()
+		return res
+	}
+
+	expected := c.ExpectedStatus
+	if len(expected) == 0 {
+		expected = []int{http.StatusOK}
+	}
+	statusOK := false
+	for _, s := range expected {
+		if s == resp.StatusCode {
+			statusOK = true
+		}
+	}
+
+	switch {
+	case !statusOK:
+		res.Reason = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	case c.MaxLatency > 0 && res.Latency > c.MaxLatency:
+		res.Reason = fmt.Sprintf("latency %s exceeds %s", res.Latency, c.MaxLatency)
+	default:
+		for _, s := range c.BodyContains {
+			if !strings.Contains(string(body), s) {
+				res.Reason = fmt.Sprintf("body does not contain '%s'", s)
+				return res
+			}
+		}
+		res.Healthy = true
+	}
+	return res
+}
+
+// DeploymentCheck verifies a completed deployment, for example by probing the site it deployed.
+type DeploymentCheck interface {
+	CheckDeployment(ctx context.Context, target EnvironmentRef, d *Deployment) *HealthReport
+}
+
+// EnvironmentHealthCheckers is a DeploymentCheck with different checks per environment. An environment without
+// checks is reported unhealthy, so a target missing from the map cannot pass unnoticed. Map it to an empty
+// HealthChecker to deliberately skip its checks.
+type EnvironmentHealthCheckers map[EnvironmentRef]*HealthChecker
+
+func (e EnvironmentHealthCheckers) CheckDeployment(ctx context.Context, target EnvironmentRef, d *Deployment) *HealthReport {
+	hc, ok := e[target]
+	if !ok || hc == nil {
+		return &HealthReport{Results: []*HealthResult{{
+			Check:  &HealthCheck{Name: target.String()},
+			Reason: "no health checks configured",
+		}}}
+	}
+	return hc.Run(ctx)
+}
+
+type DeploymentOutcome string
+
+const (
+	OutcomeHealthy   DeploymentOutcome = "healthy"
+	OutcomeUnhealthy DeploymentOutcome = "unhealthy"
+	OutcomeFailed    DeploymentOutcome = "failed"
+)
+
+// DeploymentResult is a finished deployment and its health. Health is nil if the deployment did not complete, or
+// there was no check.
+type DeploymentResult struct {
+	Deployment *Deployment
+	Outcome    DeploymentOutcome
+	Health     *HealthReport
+}
+
+// WaitForDeploymentOutcome waits for the deployment like WaitForDeployment, then runs the check if the deployment
+// completed. A completed deployment that fails the check is OutcomeUnhealthy. A nil check treats every completed
+// deployment as healthy.
+func (a *Client) WaitForDeploymentOutcome(ctx context.Context, sID string, eID string, dID int, interval time.Duration, check DeploymentCheck) (*DeploymentResult, error) {
+	d, err := a.WaitForDeployment(ctx, sID, eID, dID, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DeploymentResult{Deployment: d, Outcome: OutcomeFailed}
+	if d.State != StateCompleted {
+		return res, nil
+	}
+
+	res.Outcome = OutcomeHealthy
+	if check != nil {
+		res.Health = check.CheckDeployment(ctx, EnvironmentRef{Stack: sID, Environment: eID}, d)
+		if !res.Health.Healthy {
+			res.Outcome = OutcomeUnhealthy
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSite serves a page that returns 503 for the first warmup requests, then 200 with the body.
+func newSite(warmup int32, body string, delay time.Duration) (*httptest.Server, *int32) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		if atomic.AddInt32(&requests, 1) <= warmup {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	return ts, &requests
+}
+
+func TestHealthCheckerRetriesDuringGracePeriod(t *testing.T) {
+	site, requests := newSite(2, "<title>Home</title>", 0)
+	defer site.Close()
+
+	hc := &HealthChecker{
+		Checks:      []*HealthCheck{{URL: site.URL, BodyContains: []string{"Home"}}},
+		GracePeriod: time.Second,
+		Interval:    time.Millisecond,
+	}
+	report := hc.Run(context.Background())
+	if !report.Healthy {
+		t.Fatalf("Expected healthy site, got %s", report)
+	}
+	if report.Results[0].Attempts != 3 || atomic.LoadInt32(requests) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", report.Results[0].Attempts)
+	}
+}
+
+func TestHealthCheckerFailures(t *testing.T) {
+	site, _ := newSite(0, "maintenance mode", 20*time.Millisecond)
+	defer site.Close()
+
+	hc := &HealthChecker{
+		Checks: []*HealthCheck{
+			{Name: "status", URL: site.URL + "/missing", ExpectedStatus: []int{http.StatusNotFound}},
+			{Name: "body", URL: site.URL, BodyContains: []string{"Home"}},
+			{Name: "latency", URL: site.URL, MaxLatency: time.Millisecond},
+			{Name: "down", URL: "http://127.0.0.1:1"},
+		},
+		Interval: time.Millisecond,
+	}
+	report := hc.Run(context.Background())
+	if report.Healthy {
+		t.Fatal("Expected unhealthy report")
+	}
+
+	failures := report.Failures()
+	if len(failures) != 4 {
+		t.Fatalf("Expected 4 failures, got %s", report)
+	}
+	for _, expected := range []string{"unexpected status 200", "body does not contain 'Home'", "latency", "down:"} {
+		if !strings.Contains(report.String(), expected) {
+			t.Errorf("Expected '%s' in %s", expected, report)
+		}
+	}
+	for _, f := range failures {
+		if f.Attempts != 1 {
+			t.Errorf("Without a grace period %s should be attempted once, got %d", f.Check, f.Attempts)
+		}
+	}
+}
+
+func TestWaitForDeploymentOutcome(t *testing.T) {
+	site, _ := newSite(0, "Home", 0)
+	defer site.Close()
+
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/deploys/1": {http.StatusOK, &Deployment{ID: 1, OriginalState: "Completed"}},
+		"GET /naut/project/one/environment/uat/deploys/1":  {http.StatusOK, &Deployment{ID: 1, OriginalState: "Failed"}},
+	})
+	defer ts.Close()
+
+	checks := EnvironmentHealthCheckers{
+		EnvironmentRef{Stack: "one", Environment: "prod"}: {Checks: []*HealthCheck{{URL: site.URL, BodyContains: []string{"Home"}}}},
+	}
+	res, err := api.WaitForDeploymentOutcome(context.Background(), "one", "prod", 1, time.Millisecond, checks)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if res.Outcome != OutcomeHealthy || !res.Health.Healthy {
+		t.Errorf("Expected healthy outcome, got %s", res.Outcome)
+	}
+
+	checks[EnvironmentRef{Stack: "one", Environment: "prod"}].Checks[0].BodyContains = []string{"Welcome"}
+	res, err = api.WaitForDeploymentOutcome(context.Background(), "one", "prod", 1, time.Millisecond, checks)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if res.Outcome != OutcomeUnhealthy || res.Deployment.State != StateCompleted {
+		t.Errorf("Expected completed but unhealthy outcome, got %s", res.Outcome)
+	}
+
+	res, err = api.WaitForDeploymentOutcome(context.Background(), "one", "uat", 1, time.Millisecond, checks)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if res.Outcome != OutcomeFailed || res.Health != nil {
+		t.Errorf("Expected failed outcome without health check, got %s", res.Outcome)
+	}
+}
+
+func TestEnvironmentHealthCheckersMissingTarget(t *testing.T) {
+	checks := EnvironmentHealthCheckers{
+		EnvironmentRef{Stack: "one", Environment: "uat"}: {},
+	}
+
+	report := checks.CheckDeployment(context.Background(), EnvironmentRef{Stack: "one", Environment: "prod"}, &Deployment{ID: 1})
+	if report.Healthy || report.String() != "unhealthy (one/prod: no health checks configured)" {
+		t.Errorf("Target without checks should be unhealthy, got %s", report)
+	}
+
+	report = checks.CheckDeployment(context.Background(), EnvironmentRef{Stack: "one", Environment: "uat"}, &Deployment{ID: 1})
+	if !report.Healthy {
+		t.Errorf("Target with an empty checker should be healthy, got %s", report)
+	}
+}
+
+func TestRolloutFleetHealthCheck(t *testing.T) {
+	healthy, _ := newSite(0, "Home", 0)
+	defer healthy.Close()
+	broken, _ := newSite(100, "", 0)
+	defer broken.Close()
+
+	api, ts, _ := newMockRouter(fleetRoutes(map[string]string{
+		"a": "Completed",
+		"b": "Completed",
+	}))
+	defer ts.Close()
+
+	report, err := api.RolloutFleet(context.Background(), &FleetRollout{
+		Targets:      fleetTargets("a", "b"),
+		Deployment:   CreateDeployment{Ref: "master", RefType: RefTypeBranch, BypassAndStart: true},
+		PollInterval: time.Millisecond,
+		MaxFailures:  1,
+		Check: EnvironmentHealthCheckers{
+			EnvironmentRef{Stack: "a", Environment: "prod"}: {Checks: []*HealthCheck{{URL: healthy.URL}}},
+			EnvironmentRef{Stack: "b", Environment: "prod"}: {Checks: []*HealthCheck{{URL: broken.URL}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if report.Succeeded != 1 || report.Failed != 1 {
+		t.Errorf("Expected the unhealthy target to fail, got %+v", report)
+	}
+	if !report.Results[1].Failed() || report.Results[1].Health.Healthy {
+		t.Error("Target b should be unhealthy")
+	}
+}
@@ -0,0 +1,144 @@
+package ssp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InheritanceCycleError is returned when a chain of base environments or base stacks loops back on itself. Chain
+// lists the identifiers walked, ending with the repeated one.
+type InheritanceCycleError struct {
+	Chain []string
+}
+
+func (e *InheritanceCycleError) Error() string {
+	return fmt.Sprintf("inheritance cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// WalkEnvironmentAncestry fetches the environment, then each of its base environments in turn, and calls fn for
+// every level until fn returns false or an environment has no base. Each level is fetched only once fn has
+// accepted the previous one. The stack of each base environment is included in the request, and an error is
+// returned if the Dashboard does not report it.
+func (a *Client) WalkEnvironmentAncestry(sID string, eID string, fn func(ref EnvironmentRef, env *Environment) bool) error {
+	ref := EnvironmentRef{Stack: sID, Environment: eID}
+	seen := map[EnvironmentRef]bool{}
+	chain := []string{}
+
+	for {
+		chain = append(chain, ref.String())
+		if seen[ref] {
+			return &InheritanceCycleError{Chain: chain}
+		}
+		seen[ref] = true
+
+		env, err := a.GetEnvironment(ref.Stack, ref.Environment, IncludeBaseEnvironmentStack)
+		if err != nil {
+			return fmt.Errorf("failed fetching environment %s: '%s'", ref, err)
+		}
+		if !fn(ref, env) || env.BaseEnvironment == nil || env.BaseEnvironment.ID == "" {
+			return nil
+		}
+
+		if env.BaseEnvironment.Stack == nil || env.BaseEnvironment.Stack.ID == "" {
+			return fmt.Errorf("stack of base environment %s of %s is unknown", env.BaseEnvironment.ID, ref)
+		}
+		ref = EnvironmentRef{Stack: env.BaseEnvironment.Stack.ID, Environment: env.BaseEnvironment.ID}
+	}
+}
+
+// EnvironmentAncestry is an environment followed by the environments it inherits from, nearest first.
+type EnvironmentAncestry struct {
+	Refs         []EnvironmentRef
+	Environments []*Environment
+}
+
+// String describes the chain, for example "one/prod inherits from one/uat, which inherits from base/test".
+func (ea *EnvironmentAncestry) String() string {
+	names := make([]string, len(ea.Refs))
+	for i, ref := range ea.Refs {
+		names[i] = ref.String()
+	}
+	return describeAncestry(names)
+}
+
+// ResolveEnvironmentAncestry walks all base environments of the environment. See WalkEnvironmentAncestry.
+func (a *Client) ResolveEnvironmentAncestry(sID string, eID string) (*EnvironmentAncestry, error) {
+	ea := &EnvironmentAncestry{}
+	err := a.WalkEnvironmentAncestry(sID, eID, func(ref EnvironmentRef, env *Environment) bool {
+		ea.Refs = append(ea.Refs, ref)
+		ea.Environments = append(ea.Environments, env)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ea, nil
+}
+
+// WalkStackAncestry fetches the stack, then each of its base stacks in turn, and calls fn for every level until fn
+// returns false or a stack has no base.
+func (a *Client) WalkStackAncestry(sID string, fn func(stack *Stack) bool) error {
+	seen := map[string]bool{}
+	chain := []string{}
+
+	for {
+		chain = append(chain, sID)
+		if seen[sID] {
+			return &InheritanceCycleError{Chain: chain}
+		}
+		seen[sID] = true
+
+		stack, err := a.GetStack(sID)
+		if err != nil {
+			return fmt.Errorf("failed fetching stack %s: '%s'", sID, err)
+		}
+		if !fn(stack) || stack.BaseStack == nil || stack.BaseStack.ID == "" {
+			return nil
+		}
+		sID = stack.BaseStack.ID
+	}
+}
+
+// StackAncestry is a stack followed by the stacks it inherits from, nearest first.
+type StackAncestry struct {
+	Stacks []*Stack
+}
+
+// String describes the chain, for example "one inherits from base, which inherits from platform".
+func (sa *StackAncestry) String() string {
+	names := make([]string, len(sa.Stacks))
+	for i, s := range sa.Stacks {
+		names[i] = s.ID
+	}
+	return describeAncestry(names)
+}
+
+// ResolveStackAncestry walks all base stacks of the stack. See WalkStackAncestry.
+func (a *Client) ResolveStackAncestry(sID string) (*StackAncestry, error) {
+	sa := &StackAncestry{}
+	err := a.WalkStackAncestry(sID, func(stack *Stack) bool {
+		sa.Stacks = append(sa.Stacks, stack)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sa, nil
+}
+
+func describeAncestry(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s does not inherit", names[0])
+	}
+
+	s := fmt.Sprintf("%s inherits from %s", names[0], names[1])
+	for _, name := range names[2:] {
+		s += fmt.Sprintf(", which inherits from %s", name)
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package ssp
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolveEnvironmentAncestry(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod": {http.StatusOK, &Environment{ID: "prod", BaseEnvironment: &Environment{ID: "uat", Stack: &Stack{ID: "one"}}}},
+		"GET /naut/project/one/environment/uat": {http.StatusOK, &Environment{ID: "uat", BaseEnvironment: &Environment{
+			ID:    "test",
+			Stack: &Stack{ID: "base"},
+		}}},
+		"GET /naut/project/base/environment/test": {http.StatusOK, &Environment{ID: "test", OriginalUsage: "Test"}},
+	})
+	defer ts.Close()
+
+	ea, err := api.ResolveEnvironmentAncestry("one", "prod")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(ea.Environments) != 3 || ea.Environments[2].Usage != UsageTest {
+		t.Fatalf("Unexpected ancestry %v", ea.Refs)
+	}
+	if ea.String() != "one/prod inherits from one/uat, which inherits from base/test" {
+		t.Errorf("Unexpected description '%s'", ea)
+	}
+
+	// Stopping the walk early does not fetch the remaining levels.
+	err = api.WalkEnvironmentAncestry("one", "uat", func(ref EnvironmentRef, env *Environment) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(m.requestsTo("GET", "/naut/project/base/environment/test")) != 1 {
+		t.Error("Walk should stop when the callback returns false")
+	}
+	if include := m.requestsTo("GET", "/naut/project/one/environment/prod")[0].query.Get("include"); include != IncludeBaseEnvironmentStack {
+		t.Errorf("Expected the base environment stack to be included, got '%s'", include)
+	}
+}
+
+func TestResolveEnvironmentAncestryUnknownStack(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod": {http.StatusOK, &Environment{ID: "prod", BaseEnvironment: &Environment{ID: "uat"}}},
+	})
+	defer ts.Close()
+
+	if _, err := api.ResolveEnvironmentAncestry("one", "prod"); err == nil {
+		t.Error("Expected error for a base environment without a stack")
+	}
+	if len(m.requestsTo("GET", "/naut/project/one/environment/uat")) != 0 {
+		t.Error("Base environment should not be guessed to be in the same stack")
+	}
+}
+
+func TestResolveEnvironmentAncestryCycle(t *testing.T) {
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/a": {http.StatusOK, &Environment{ID: "a", BaseEnvironment: &Environment{ID: "b", Stack: &Stack{ID: "one"}}}},
+		"GET /naut/project/one/environment/b": {http.StatusOK, &Environment{ID: "b", BaseEnvironment: &Environment{ID: "a", Stack: &Stack{ID: "one"}}}},
+	})
+	defer ts.Close()
+
+	_, err := api.ResolveEnvironmentAncestry("one", "a")
+	cycle, ok := err.(*InheritanceCycleError)
+	if !ok {
+		t.Fatalf("Expected InheritanceCycleError, got %v", err)
+	}
+	if cycle.Error() != "inheritance cycle: one/a -> one/b -> one/a" {
+		t.Errorf("Unexpected error '%s'", cycle)
+	}
+}
+
+func TestResolveStackAncestry(t *testing.T) {
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one":      {http.StatusOK, &Stack{ID: "one", BaseStack: &Stack{ID: "base"}}},
+		"GET /naut/project/base":     {http.StatusOK, &Stack{ID: "base", BaseStack: &Stack{ID: "platform"}}},
+		"GET /naut/project/platform": {http.StatusOK, &Stack{ID: "platform", BaseStack: &Stack{ID: "one"}}},
+		"GET /naut/project/single":   {http.StatusOK, &Stack{ID: "single"}},
+	})
+	defer ts.Close()
+
+	if _, err := api.ResolveStackAncestry("one"); err == nil {
+		t.Error("Expected cycle error")
+	} else if err.Error() != "inheritance cycle: one -> base -> platform -> one" {
+		t.Errorf("Unexpected error '%s'", err)
+	}
+
+	sa, err := api.ResolveStackAncestry("single")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if sa.String() != "single does not inherit" {
+		t.Errorf("Unexpected description '%s'", sa)
+	}
+
+	var visited []string
+	err = api.WalkStackAncestry("base", func(s *Stack) bool {
+		visited = append(visited, s.ID)
+		return s.ID != "platform"
+	})
+	if err != nil || len(visited) != 2 {
+		t.Errorf("Expected walk to stop at platform, got %v (%v)", visited, err)
+	}
+}
@@ -0,0 +1,96 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+// InstanceType describes an instance size that an environment can be resized to.
+type InstanceType struct {
+	ID          string  `jsonapi:"primary,instance_types"`
+	Name        string  `jsonapi:"attr,name"`
+	Description string  `jsonapi:"attr,description"`
+	CPUs        int     `jsonapi:"attr,cpus"`
+	MemoryGiB   float64 `jsonapi:"attr,memory_gib"`
+}
+
+func (it *InstanceType) String() string {
+	return fmt.Sprintf("%s (%d vCPU, %g GiB)", it.Name, it.CPUs, it.MemoryGiB)
+}
+
+// ListInstanceTypes returns the instance types available to the environment, smallest first.
+func (a *Client) ListInstanceTypes(sID string, eID string) ([]*InstanceType, error) {
+	url := fmt.Sprintf("naut/project/%s/environment/%s/instancetypes", sID, eID)
+	r, err := a.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	items, err := jsonapi.UnmarshalManyPayload(r, reflect.TypeOf(new(InstanceType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling instance types: '%s'", err)
+	}
+
+	types := make([]*InstanceType, len(items))
+	for i, item := range items {
+		types[i] = item.(*InstanceType)
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].CPUs != types[j].CPUs {
+			return types[i].CPUs < types[j].CPUs
+		}
+		return types[i].MemoryGiB < types[j].MemoryGiB
+	})
+
+	return types, nil
+}
+
+// validateInstanceType checks that the instance type is available to the environment.
+func (a *Client) validateInstanceType(sID string, eID string, name string) error {
+	types, err := a.ListInstanceTypes(sID, eID)
+	if err != nil {
+		return fmt.Errorf("failed listing instance types: '%s'", err)
+	}
+
+	names := make([]string, len(types))
+	for i, it := range types {
+		if it.Name == name {
+			return nil
+		}
+		names[i] = it.Name
+	}
+
+	return fmt.Errorf("instance type '%s' is not available, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// WaitForInstanceType polls the environment every interval until its InstanceType matches, or the context is done.
+// Use a context with a timeout to limit how long to wait for a resize. A non-positive interval means
+// DefaultPollInterval.
+func (a *Client) WaitForInstanceType(ctx context.Context, sID string, eID string, instanceType string, interval time.Duration) (*Environment, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		env, err := a.GetEnvironment(sID, eID)
+		if err != nil {
+			return nil, err
+		}
+		if env.InstanceType == instanceType {
+			return env, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return env, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ssp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// instanceTypeRoutes serves the catalog and accepts resizes. The environment is only served if current is set.
+func instanceTypeRoutes(current func() interface{}) map[string]mockRoute {
+	routes := map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/instancetypes": {http.StatusOK, []*InstanceType{
+			{ID: "2", Name: "t2.medium", CPUs: 2, MemoryGiB: 4},
+			{ID: "1", Name: "t2.small", CPUs: 1, MemoryGiB: 2},
+		}},
+		"POST /naut/project/one/environment/prod/sspattributes": {http.StatusOK, nil},
+	}
+	if current != nil {
+		routes["GET /naut/project/one/environment/prod"] = mockRoute{http.StatusOK, current}
+	}
+	return routes
+}
+
+func TestListInstanceTypes(t *testing.T) {
+	api, ts, _ := newMockRouter(instanceTypeRoutes(nil))
+	defer ts.Close()
+
+	types, err := api.ListInstanceTypes("one", "prod")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(types) != 2 || types[0].Name != "t2.small" {
+		t.Fatalf("Expected instance types sorted by size, got %v", types)
+	}
+	if types[1].String() != "t2.medium (2 vCPU, 4 GiB)" {
+		t.Errorf("Unexpected description '%s'", types[1])
+	}
+}
+
+func TestUpdateInstanceTypeUnavailable(t *testing.T) {
+	api, ts, m := newMockRouter(instanceTypeRoutes(nil))
+	defer ts.Close()
+
+	err := api.UpdateInstanceType("one", "prod", &UpdateInstanceType{InstanceType: "m4.16xlarge"})
+	if err == nil {
+		t.Fatal("Expected error for unavailable instance type")
+	}
+	if len(m.requestsTo("POST", "/naut/project/one/environment/prod/sspattributes")) != 0 {
+		t.Error("Unavailable instance type should not be sent")
+	}
+}
+
+func TestWaitForInstanceType(t *testing.T) {
+	polls := 0
+	api, ts, _ := newMockRouter(instanceTypeRoutes(func() interface{} {
+		polls++
+		if polls < 3 {
+			return &Environment{ID: "prod", InstanceType: "t2.small"}
+		}
+		return &Environment{ID: "prod", InstanceType: "t2.medium"}
+	}))
+	defer ts.Close()
+
+	if err := api.UpdateInstanceType("one", "prod", &UpdateInstanceType{InstanceType: "t2.medium"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	env, err := api.WaitForInstanceType(context.Background(), "one", "prod", "t2.medium", time.Millisecond)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if env.InstanceType != "t2.medium" || polls != 3 {
+		t.Errorf("Expected resize after 3 polls, got %s after %d", env.InstanceType, polls)
+	}
+}
+
+func TestWaitForInstanceTypeTimeout(t *testing.T) {
+	api, ts, _ := newMockRouter(instanceTypeRoutes(func() interface{} {
+		return &Environment{ID: "prod", InstanceType: "t2.small"}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	env, err := api.WaitForInstanceType(ctx, "one", "prod", "t2.medium", time.Millisecond)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if env == nil || env.InstanceType != "t2.small" {
+		t.Error("Expected the last seen environment")
+	}
+}
+
+func TestWaitForInstanceTypeDefaultInterval(t *testing.T) {
+	api, ts, m := newMockRouter(instanceTypeRoutes(func() interface{} {
+		return &Environment{ID: "prod", InstanceType: "t2.small"}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := api.WaitForInstanceType(ctx, "one", "prod", "t2.medium", 0); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if polls := len(m.requestsTo("GET", "/naut/project/one/environment/prod")); polls != 1 {
+		t.Errorf("Zero interval should not busy-loop, got %d polls", polls)
+	}
+}
@@ -0,0 +1,81 @@
+package ssp
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaintenanceWindow is the weekly window in which an environment may be disrupted. Day and Start are on the wall
+// clock of Location, so the window stays at the same local time across DST changes. Windows may cross midnight.
+//
+// The zero value is an unspecified window: Next and Until return ErrMaintenanceUnspecified, and Contains is
+// always false.
+type MaintenanceWindow struct {
+	Day      time.Weekday
+	Start    time.Duration
+	Duration time.Duration
+	Location *time.Location
+}
+
+// Specified reports whether the window can be used for scheduling.
+func (w MaintenanceWindow) Specified() bool {
+	return w.Location != nil && w.Duration > 0 && w.Start >= 0 && w.Start < 24*time.Hour
+}
+
+// Next returns the first occurrence of the window that ends after the given time. If the time falls inside an
+// occurrence, that occurrence is returned, so start may be before the given time.
+func (w MaintenanceWindow) Next(after time.Time) (time.Time, time.Time, error) {
+	if !w.Specified() {
+		return time.Time{}, time.Time{}, ErrMaintenanceUnspecified
+	}
+
+	local := after.In(w.Location)
+	offset := (int(w.Day) - int(local.Weekday()) + 7) % 7
+
+	// Start a week back to catch an occurrence that is still running.
+	for week := -1; ; week++ {
+		start := w.at(local.Year(), local.Month(), local.Day()+offset+week*7)
+		end := start.Add(w.Duration)
+		if end.After(after) {
+			return start, end, nil
+		}
+	}
+}
+
+// Contains reports whether the time falls inside an occurrence of the window.
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	start, _, err := w.Next(t)
+	return err == nil && !start.After(t)
+}
+
+// Until returns how long it is until the next occurrence of the window starts, or zero if now is inside one.
+func (w MaintenanceWindow) Until(now time.Time) (time.Duration, error) {
+	start, _, err := w.Next(now)
+	if err != nil {
+		return 0, err
+	}
+	if !start.After(now) {
+		return 0, nil
+	}
+	return start.Sub(now), nil
+}
+
+func (w MaintenanceWindow) String() string {
+	if !w.Specified() {
+		return "unspecified"
+	}
+	end := (w.Start + w.Duration) % (24 * time.Hour)
+	return fmt.Sprintf("%s %s-%s %s", w.Day, clock(w.Start), clock(end), w.Location)
+}
+
+// at returns the start of the window on the given local date. Dates out of range are normalised by time.Date.
+func (w MaintenanceWindow) at(year int, month time.Month, day int) time.Time {
+	h := w.Start / time.Hour
+	m := (w.Start % time.Hour) / time.Minute
+	s := (w.Start % time.Minute) / time.Second
+	return time.Date(year, month, day, int(h), int(m), int(s), 0, w.Location)
+}
+
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int((d%time.Hour)/time.Minute))
+}
@@ -0,0 +1,119 @@
+package ssp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	loc, _ := time.LoadLocation("Pacific/Auckland")
+	w := MaintenanceWindow{Day: time.Sunday, Start: 23 * time.Hour, Duration: 3 * time.Hour, Location: loc}
+
+	if w.String() != "Sunday 23:00-02:00 Pacific/Auckland" {
+		t.Errorf("Unexpected string '%s'", w)
+	}
+
+	inside := time.Date(2017, 3, 27, 1, 30, 0, 0, loc)
+	if !w.Contains(inside) {
+		t.Error("Monday 01:30 should be inside the window started on Sunday")
+	}
+	if until, err := w.Until(inside); err != nil || until != 0 {
+		t.Errorf("Expected zero until inside the window, got %s (%v)", until, err)
+	}
+
+	outside := time.Date(2017, 3, 27, 2, 0, 0, 0, loc)
+	if w.Contains(outside) {
+		t.Error("Window end should not be inside the window")
+	}
+	until, err := w.Until(outside)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// The clocks go back an hour on 2 April, so the next window is an hour further away.
+	if until != 6*24*time.Hour+22*time.Hour {
+		t.Errorf("Unexpected time until the next window %s", until)
+	}
+}
+
+func TestMaintenanceWindowDST(t *testing.T) {
+	// Auckland moves from NZDT to NZST on Sunday 2 April 2017 at 03:00, which repeats 02:00-03:00.
+	loc, _ := time.LoadLocation("Pacific/Auckland")
+	w := MaintenanceWindow{Day: time.Sunday, Start: 4 * time.Hour, Duration: time.Hour, Location: loc}
+
+	start, _, err := w.Next(time.Date(2017, 3, 30, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if start.Hour() != 4 || start.Day() != 2 {
+		t.Errorf("Window should start at 04:00 local time, got %s", start)
+	}
+	if _, offset := start.Zone(); offset != 12*60*60 {
+		t.Errorf("Window should start in standard time, got offset %d", offset)
+	}
+}
+
+func TestMaintenanceWindowUnspecified(t *testing.T) {
+	w := MaintenanceWindow{}
+	if w.Specified() || w.Contains(time.Now()) {
+		t.Error("Zero window should be unspecified")
+	}
+	if _, err := w.Until(time.Now()); err != ErrMaintenanceUnspecified {
+		t.Errorf("Expected ErrMaintenanceUnspecified, got %v", err)
+	}
+	if w.String() != "unspecified" {
+		t.Errorf("Unexpected string '%s'", w)
+	}
+}
+
+func TestGetEnvironmentMaintenanceWindow(t *testing.T) {
+	in := &Environment{
+		ID:                          "one",
+		OriginalMaintenanceDay:      "Wednesday",
+		OriginalMaintenanceTime:     "22:00",
+		OriginalMaintenanceDuration: "2:00",
+		OriginalMaintenanceTz:       "Pacific/Auckland",
+	}
+	api, ts := newMockDashboard(in, http.StatusOK)
+	defer ts.Close()
+
+	out, err := api.GetEnvironment("one", "prod")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if out.MaintenanceWindow.String() != "Wednesday 22:00-00:00 Pacific/Auckland" {
+		t.Errorf("Unexpected maintenance window '%s'", out.MaintenanceWindow)
+	}
+
+	in.OriginalMaintenanceTz = ""
+	in.OriginalMaintenanceDay = "Someday"
+	out, err = api.GetEnvironment("one", "prod")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if out.MaintenanceWindow.Specified() {
+		t.Error("Invalid maintenance window should be unspecified")
+	}
+}
+
+func TestGetEnvironmentMaintenanceWindowWithoutTimezone(t *testing.T) {
+	for _, tz := range []string{"", "Local"} {
+		in := &Environment{
+			ID:                          "one",
+			OriginalMaintenanceDay:      "Wednesday",
+			OriginalMaintenanceTime:     "22:00",
+			OriginalMaintenanceDuration: "2:00",
+			OriginalMaintenanceTz:       tz,
+		}
+		api, ts := newMockDashboard(in, http.StatusOK)
+
+		out, err := api.GetEnvironment("one", "prod")
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if out.MaintenanceWindow.Specified() || !out.MaintenanceUnspecified {
+			t.Errorf("Maintenance window with timezone '%s' should be unspecified", tz)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ssp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+// ManifestDriftColumns are the columns of the table and CSV output of a drift report, in order.
+var ManifestDriftColumns = []string{
+	"stack", "environment", "current", "desired", "drifted", "releases_behind", "released", "days_since_release",
+}
+
+// ManifestDrift compares the manifest of an environment against the desired manifest and the latest release.
+// Release is the release matching the current manifest, and is nil if there is none. ReleasesBehind counts the
+// releases newer than the current manifest, and Age is the time since the matching release. Both are only known
+// if the current manifest is Valid.
+type ManifestDrift struct {
+	Environment    EnvironmentRef   `json:"environment"`
+	Current        string           `json:"current"`
+	Desired        string           `json:"desired"`
+	Valid          bool             `json:"valid"`
+	Drifted        bool             `json:"drifted"`
+	ReleasesBehind int              `json:"releases_behind"`
+	Release        *ManifestRelease `json:"-"`
+	Released       time.Time        `json:"released"`
+	Age            time.Duration    `json:"-"`
+}
+
+// NewManifestDrift compares the environment against the given releases at the time now. The current and desired
+// manifests are compared as versions, so "2.3" and "v2.3.0" are the same, unless one of them could not be parsed.
+func NewManifestDrift(ref EnvironmentRef, env *Environment, releases []*ManifestRelease, now time.Time) *ManifestDrift {
+	md := &ManifestDrift{
+		Valid:       env.CurrentManifestShaValid,
+		Environment: ref,
+		Current:     env.OriginalCurrentManifestSha,
+		Desired:     env.OriginalDesiredManifestSha,
+		Drifted:     env.OriginalCurrentManifestSha != env.OriginalDesiredManifestSha,
+	}
+	if env.CurrentManifestShaValid && env.DesiredManifestShaValid {
+		md.Drifted = !env.CurrentManifestSha.Equals(env.DesiredManifestSha)
+	}
+
+	if !env.CurrentManifestShaValid {
+		return md
+	}
+	for _, r := range releases {
+		if r.ShaValid && r.Sha.GT(env.CurrentManifestSha) {
+			md.ReleasesBehind++
+		}
+		if r.ShaValid && r.Sha.Equals(env.CurrentManifestSha) {
+			md.Release = r
+		}
+	}
+	if md.Release != nil {
+		md.Released = md.Release.Released
+		md.Age = now.Sub(md.Release.Released)
+	}
+
+	return md
+}
+
+// ManifestDriftReport lists the manifest drift of all visible environments, ordered by stack and environment.
+//
+// Stacks whose environments could not be listed are reported in Errors rather than failing the whole report.
+type ManifestDriftReport struct {
+	Generated    time.Time        `json:"generated"`
+	Latest       string           `json:"latest"`
+	Environments []*ManifestDrift `json:"environments"`
+	Errors       []error          `json:"-"`
+}
+
+// Drifted returns the environments whose current manifest differs from the desired one.
+func (r *ManifestDriftReport) Drifted() []*ManifestDrift {
+	drifted := []*ManifestDrift{}
+	for _, md := range r.Environments {
+		if md.Drifted {
+			drifted = append(drifted, md)
+		}
+	}
+	return drifted
+}
+
+// WriteTable writes the report as an aligned plain text table.
+func (r *ManifestDriftReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, row := range r.rows() {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprint(tw, "\n")
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the report as CSV with a header row.
+func (r *ManifestDriftReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(r.rows()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func (r *ManifestDriftReport) rows() [][]string {
+	rows := [][]string{ManifestDriftColumns}
+	for _, md := range r.Environments {
+		behind, days := "", ""
+		if md.Valid {
+			behind = strconv.Itoa(md.ReleasesBehind)
+		}
+		if md.Release != nil {
+			days = strconv.Itoa(int(md.Age.Hours() / 24))
+		}
+		rows = append(rows, []string{
+			md.Environment.Stack, md.Environment.Environment, md.Current, md.Desired, strconv.FormatBool(md.Drifted),
+			behind, exportTime(md.Released), days,
+		})
+	}
+	return rows
+}
+
+// GetManifestDriftReport compares the manifests of all environments visible to the current user against the
+// manifest releases.
+func (a *Client) GetManifestDriftReport() (*ManifestDriftReport, error) {
+	releases, err := a.ListManifestReleases()
+	if err != nil {
+		return nil, err
+	}
+	stacks, err := a.ListStacks()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ManifestDriftReport{
+		Generated:    time.Now(),
+		Environments: []*ManifestDrift{},
+	}
+
+	var latest *semver.Version
+	for _, r := range releases {
+		if !r.ShaValid {
+			continue
+		}
+		if latest == nil || r.Sha.GT(*latest) {
+			latest = &r.Sha
+		}
+	}
+	if latest != nil {
+		report.Latest = latest.String()
+	}
+
+	for _, s := range stacks {
+		envs, err := a.ListEnvironments(s.ID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("failed listing environments of %s: '%s'", s.ID, err))
+			continue
+		}
+		for _, env := range envs {
+			ref := EnvironmentRef{Stack: s.ID, Environment: env.ID}
+			report.Environments = append(report.Environments, NewManifestDrift(ref, env, releases, report.Generated))
+		}
+	}
+
+	sort.Slice(report.Environments, func(i, j int) bool {
+		x, y := report.Environments[i].Environment, report.Environments[j].Environment
+		if x.Stack != y.Stack {
+			return x.Stack < y.Stack
+		}
+		return x.Environment < y.Environment
+	})
+
+	return report, nil
+}
@@ -0,0 +1,109 @@
+package ssp
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetManifestDriftReport(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/manifestreleases": {http.StatusOK, []*ManifestRelease{
+			{ID: "1", OriginalSha: "1.0.0", Released: now.Add(-30 * 24 * time.Hour)},
+			{ID: "2", OriginalSha: "1.1.0", Released: now.Add(-10 * 24 * time.Hour)},
+			{ID: "3", OriginalSha: "1.2.0", Released: now.Add(-24 * time.Hour)},
+		}},
+		"GET /naut/projects": {http.StatusOK, []*Stack{{ID: "two"}, {ID: "one"}, {ID: "broken"}}},
+		"GET /naut/project/one/environments": {http.StatusOK, []*Environment{
+			{ID: "uat", OriginalCurrentManifestSha: "1.2.0", OriginalDesiredManifestSha: "1.2.0"},
+			{ID: "prod", OriginalCurrentManifestSha: "1.0.0", OriginalDesiredManifestSha: "1.2.0"},
+		}},
+		"GET /naut/project/two/environments": {http.StatusOK, []*Environment{
+			{ID: "prod", OriginalCurrentManifestSha: "0.9.0", OriginalDesiredManifestSha: "0.9.0"},
+		}},
+	})
+	defer ts.Close()
+
+	report, err := api.GetManifestDriftReport()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(report.Errors) != 1 {
+		t.Errorf("Expected an error for the broken stack, got %v", report.Errors)
+	}
+	if report.Latest != "1.2.0" {
+		t.Errorf("Unexpected latest release %s", report.Latest)
+	}
+	if len(report.Environments) != 3 {
+		t.Fatalf("Expected 3 environments, got %d", len(report.Environments))
+	}
+
+	prod := report.Environments[0]
+	if prod.Environment.String() != "one/prod" {
+		t.Fatalf("Expected environments sorted by stack, got %s", prod.Environment)
+	}
+	if !prod.Drifted || prod.ReleasesBehind != 2 {
+		t.Errorf("Expected one/prod to drift 2 releases behind, got %+v", prod)
+	}
+	if prod.Release == nil || prod.Age < 30*24*time.Hour {
+		t.Errorf("Expected release 30 days old, got %s", prod.Age)
+	}
+
+	unknown := report.Environments[2]
+	if unknown.Drifted || unknown.Release != nil || unknown.ReleasesBehind != 3 {
+		t.Errorf("Unexpected drift for unreleased manifest %+v", unknown)
+	}
+
+	drifted := report.Drifted()
+	if len(drifted) != 1 || drifted[0] != prod {
+		t.Errorf("Expected only one/prod to drift, got %v", drifted)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := report.WriteCSV(buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != strings.Join(ManifestDriftColumns, ",") {
+		t.Fatalf("Unexpected CSV:\n%s", buf)
+	}
+	if !strings.HasPrefix(lines[1], "one,prod,1.0.0,1.2.0,true,2,") || !strings.HasSuffix(lines[1], ",30") {
+		t.Errorf("Unexpected CSV row %s", lines[1])
+	}
+	if lines[3] != "two,prod,0.9.0,0.9.0,false,3,," {
+		t.Errorf("Unexpected CSV row %s", lines[3])
+	}
+
+	buf.Reset()
+	if err := report.WriteTable(buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.HasPrefix(buf.String(), "stack  environment  current") {
+		t.Errorf("Unexpected table:\n%s", buf)
+	}
+}
+
+func TestNewManifestDriftComparesVersions(t *testing.T) {
+	tests := []struct {
+		current string
+		desired string
+		drifted bool
+	}{
+		{"2.3", "2.3.0", false},
+		{"v2.3.0", "2.3.0", false},
+		{"2.3.0", "2.3.1", true},
+		{"unknown", "2.3.0", true},
+		{"custom", "custom", false},
+	}
+	for _, test := range tests {
+		env := &Environment{OriginalCurrentManifestSha: test.current, OriginalDesiredManifestSha: test.desired}
+		postProcessEnvironment(env)
+		md := NewManifestDrift(EnvironmentRef{Stack: "one", Environment: "prod"}, env, nil, time.Now())
+		if md.Drifted != test.drifted {
+			t.Errorf("Expected %s to %s drifted to be %t", test.current, test.desired, test.drifted)
+		}
+	}
+}
@@ -10,8 +10,10 @@ import (
 )
 
 type ManifestRelease struct {
-	ID          string `jsonapi:"primary,manifestreleases"`
-	Sha         semver.Version
+	ID  string `jsonapi:"primary,manifestreleases"`
+	Sha semver.Version
+	// ShaValid is false if OriginalSha could not be parsed, in which case Sha is the zero version.
+	ShaValid    bool
 	Released    time.Time `jsonapi:"attr,released_unix"`
 	OriginalSha string    `jsonapi:"attr,sha"`
 }
@@ -31,7 +33,8 @@ func (a *Client) ListManifestReleases() ([]*ManifestRelease, error) {
 	releases := make([]*ManifestRelease, len(items))
 	for i, item := range items {
 		releases[i] = item.(*ManifestRelease)
-		releases[i].Sha, _ = semver.Make(releases[i].OriginalSha)
+		sha, err := ParseManifestVersion(releases[i].OriginalSha)
+		releases[i].Sha, releases[i].ShaValid = sha, err == nil
 	}
 
 	return releases, nil
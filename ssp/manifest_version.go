@@ -0,0 +1,240 @@
+package ssp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// ParseManifestVersion parses a manifest SHA as a semantic version. It is more tolerant than semver.Make: a
+// leading "v" is ignored, and partial versions such as "2.3" or "2" are completed with zeros. Pre-release and
+// build metadata are kept, so "2.3-beta.1+build" is "2.3.0-beta.1+build".
+func ParseManifestVersion(s string) (semver.Version, error) {
+	v, _, err := parsePartialVersion(s)
+	return v, err
+}
+
+// parsePartialVersion also returns how many of the major, minor and patch components were given.
+func parsePartialVersion(s string) (semver.Version, int, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	core, meta := s, ""
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		core, meta = s[:i], s[i:]
+	}
+
+	parts := strings.Split(core, ".")
+	if core == "" || len(parts) > 3 {
+		return semver.Version{}, 0, fmt.Errorf("invalid version '%s'", s)
+	}
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 64); err != nil {
+			return semver.Version{}, 0, fmt.Errorf("invalid version '%s'", s)
+		}
+	}
+	given := len(parts)
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+
+	v, err := semver.Make(strings.Join(parts, ".") + meta)
+	if err != nil {
+		return semver.Version{}, 0, fmt.Errorf("invalid version '%s': %s", s, err)
+	}
+	return v, given, nil
+}
+
+// ManifestConstraint is a composer-style version constraint, such as "^2.3", "~2.3.1", ">=2.0 <3.0", "2.3.*" or
+// "1.0 - 2.0". Constraints separated by spaces or commas must all match, and "||" separates alternatives. An
+// operator may be followed by a space, as in ">= 2.0".
+type ManifestConstraint struct {
+	raw   string
+	match semver.Range
+}
+
+// ParseManifestConstraint parses a composer-style version constraint.
+func ParseManifestConstraint(s string) (*ManifestConstraint, error) {
+	var match semver.Range
+	for _, alternative := range strings.Split(strings.Replace(s, "||", "|", -1), "|") {
+		fields := strings.Fields(strings.Replace(alternative, ",", " ", -1))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid constraint '%s'", s)
+		}
+
+		var all semver.Range
+		for i := 0; i < len(fields); i++ {
+			var r semver.Range
+			var err error
+			switch {
+			case i+2 < len(fields) && fields[i+1] == "-":
+				r, err = hyphenRange(fields[i], fields[i+2])
+				i += 2
+			case i+1 < len(fields) && isConstraintOperator(fields[i]):
+				// A space after the operator, as in ">= 2.0".
+				r, err = constraintRange(fields[i] + fields[i+1])
+				i++
+			default:
+				r, err = constraintRange(fields[i])
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint '%s': %s", s, err)
+			}
+			if all == nil {
+				all = r
+			} else {
+				all = all.AND(r)
+			}
+		}
+
+		if match == nil {
+			match = all
+		} else {
+			match = match.OR(all)
+		}
+	}
+
+	return &ManifestConstraint{raw: s, match: match}, nil
+}
+
+// Check reports whether the version satisfies the constraint.
+func (c *ManifestConstraint) Check(v semver.Version) bool {
+	return c.match(v)
+}
+
+func (c *ManifestConstraint) String() string {
+	return c.raw
+}
+
+// constraintOperators are checked in order, so longer operators come before their prefixes.
+var constraintOperators = []string{">=", "<=", "!=", "==", ">", "<", "=", "^", "~"}
+
+func isConstraintOperator(s string) bool {
+	for _, op := range constraintOperators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+func constraintRange(s string) (semver.Range, error) {
+	if s == "*" {
+		return func(semver.Version) bool { return true }, nil
+	}
+	if strings.HasSuffix(s, ".*") {
+		v, given, err := parsePartialVersion(strings.TrimSuffix(s, ".*"))
+		if err != nil || given > 2 {
+			return nil, fmt.Errorf("invalid wildcard '%s'", s)
+		}
+		return between(v, bump(v, given-1)), nil
+	}
+
+	for _, op := range constraintOperators {
+		if !strings.HasPrefix(s, op) {
+			continue
+		}
+		v, given, err := parsePartialVersion(strings.TrimPrefix(s, op))
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case ">=":
+			return func(o semver.Version) bool { return o.GTE(v) }, nil
+		case "<=":
+			return func(o semver.Version) bool { return o.LTE(v) }, nil
+		case "!=":
+			return func(o semver.Version) bool { return o.NE(v) }, nil
+		case ">":
+			return func(o semver.Version) bool { return o.GT(v) }, nil
+		case "<":
+			return func(o semver.Version) bool { return o.LT(v) }, nil
+		case "^":
+			// The first non-zero component may not change: ^1.2 is <2.0.0, ^0.3 is <0.4.0.
+			switch {
+			case v.Major > 0 || given == 1:
+				return between(v, bump(v, 0)), nil
+			case v.Minor > 0 || given == 2:
+				return between(v, bump(v, 1)), nil
+			default:
+				return between(v, bump(v, 2)), nil
+			}
+		case "~":
+			// The last given component may change: ~1.2 is <2.0.0, ~1.2.3 is <1.3.0.
+			if given == 1 {
+				return between(v, bump(v, 0)), nil
+			}
+			return between(v, bump(v, given-2)), nil
+		default:
+			return func(o semver.Version) bool { return o.EQ(v) }, nil
+		}
+	}
+
+	v, err := ParseManifestVersion(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(o semver.Version) bool { return o.EQ(v) }, nil
+}
+
+// hyphenRange matches from to to inclusive. A partial upper bound includes everything it covers, so "1.0 - 2.1"
+// is <2.2.0.
+func hyphenRange(from string, to string) (semver.Range, error) {
+	lower, _, err := parsePartialVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	upper, given, err := parsePartialVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	if given < 3 {
+		return between(lower, bump(upper, given-1)), nil
+	}
+	return func(o semver.Version) bool { return o.GTE(lower) && o.LTE(upper) }, nil
+}
+
+// between matches versions from lower inclusive to upper exclusive. Pre-releases of upper are excluded too, so
+// ^2.2 does not match 3.0.0-beta.1.
+func between(lower semver.Version, upper semver.Version) semver.Range {
+	return func(o semver.Version) bool {
+		if len(o.Pre) > 0 && o.Major == upper.Major && o.Minor == upper.Minor && o.Patch == upper.Patch {
+			return false
+		}
+		return o.GTE(lower) && o.LT(upper)
+	}
+}
+
+// bump increments the major (0), minor (1) or patch (2) component, resetting the ones after it.
+func bump(v semver.Version, component int) semver.Version {
+	switch component {
+	case 0:
+		return semver.Version{Major: v.Major + 1}
+	case 1:
+		return semver.Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// MatchingReleases returns the releases with a valid version that satisfies the constraint.
+func MatchingReleases(releases []*ManifestRelease, c *ManifestConstraint) []*ManifestRelease {
+	matching := []*ManifestRelease{}
+	for _, r := range releases {
+		if r.ShaValid && c.Check(r.Sha) {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+// LatestMatchingRelease returns the highest release that satisfies the constraint, or nil if none does.
+func LatestMatchingRelease(releases []*ManifestRelease, c *ManifestConstraint) *ManifestRelease {
+	var latest *ManifestRelease
+	for _, r := range MatchingReleases(releases, c) {
+		if latest == nil || r.Sha.GT(latest.Sha) {
+			latest = r
+		}
+	}
+	return latest
+}
@@ -0,0 +1,109 @@
+package ssp
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func TestParseManifestVersion(t *testing.T) {
+	valid := map[string]string{
+		"2":                  "2.0.0",
+		"2.3":                "2.3.0",
+		"v2.3.4":             "2.3.4",
+		"2.3-beta.1":         "2.3.0-beta.1",
+		"2.3.4-rc.1+build.5": "2.3.4-rc.1+build.5",
+		"2+build":            "2.0.0+build",
+	}
+	for in, expected := range valid {
+		v, err := ParseManifestVersion(in)
+		if err != nil {
+			t.Errorf("%s", err)
+			continue
+		}
+		if v.String() != expected {
+			t.Errorf("Expected '%s' to parse as %s, got %s", in, expected, v)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "2.3.4.5", "2..3", "2.x", "2.3-"} {
+		if _, err := ParseManifestVersion(in); err == nil {
+			t.Errorf("Expected error parsing '%s'", in)
+		}
+	}
+}
+
+func TestManifestConstraint(t *testing.T) {
+	tests := map[string]map[string]bool{
+		"^2.3":          {"2.3.0": true, "2.9.9": true, "3.0.0": false, "2.2.9": false},
+		"^0.3":          {"0.3.5": true, "0.4.0": false},
+		"^0.0.3":        {"0.0.3": true, "0.0.4": false},
+		"^2.0":          {"2.1.0-rc.1": true, "3.0.0-beta.1": false},
+		"~2.3.1":        {"2.3.1": true, "2.3.9": true, "2.4.0": false, "2.3.0": false},
+		"~2.3":          {"2.3.0": true, "2.9.0": true, "3.0.0": false},
+		">=2.0 <3.0":    {"2.0.0": true, "2.5.0": true, "3.0.0": false, "1.9.9": false},
+		">=2.0,<3.0":    {"2.5.0": true, "3.0.0": false},
+		"2.3.*":         {"2.3.7": true, "2.4.0": false},
+		"2.3":           {"2.3.0": true, "2.3.1": false},
+		"1.0 - 2.1":     {"1.0.0": true, "2.1.9": true, "2.2.0": false},
+		"1.0.0 - 2.1.0": {"2.1.0": true, "2.1.1": false},
+		"^1.2 || ^3.0":  {"1.5.0": true, "2.0.0": false, "3.1.0": true},
+		"!=2.3.0":       {"2.3.0": false, "2.3.1": true},
+		">= 2.0 < 3.0":  {"2.0.0": true, "3.0.0": false},
+		"^ 2.3, != 2.4": {"2.3.5": true, "2.4.0": false, "2.5.0": true},
+		"*":             {"0.0.1": true},
+	}
+	for constraint, versions := range tests {
+		c, err := ParseManifestConstraint(constraint)
+		if err != nil {
+			t.Errorf("%s", err)
+			continue
+		}
+		for v, expected := range versions {
+			if c.Check(semver.MustParse(v)) != expected {
+				t.Errorf("Expected '%s' matching %s to be %t", c, v, expected)
+			}
+		}
+	}
+
+	for _, in := range []string{"", "^", ">=abc", "2.3.4.*", "^1.0 ||", ">= abc", "2.0 >="} {
+		if _, err := ParseManifestConstraint(in); err == nil {
+			t.Errorf("Expected error parsing constraint '%s'", in)
+		}
+	}
+}
+
+func TestLatestMatchingRelease(t *testing.T) {
+	api, ts := newMockDashboard([]*ManifestRelease{
+		{ID: "1", OriginalSha: "2.2"},
+		{ID: "2", OriginalSha: "2.4.1"},
+		{ID: "3", OriginalSha: "3.0.0-beta.1"},
+		{ID: "4", OriginalSha: "not-a-version"},
+	}, http.StatusOK)
+	defer ts.Close()
+
+	releases, err := api.ListManifestReleases()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !releases[0].ShaValid || releases[0].Sha.String() != "2.2.0" {
+		t.Error("Partial release version parsed incorrectly")
+	}
+	if releases[3].ShaValid {
+		t.Error("Invalid release version should be reported")
+	}
+
+	c, _ := ParseManifestConstraint("^2.2")
+	if matching := MatchingReleases(releases, c); len(matching) != 2 {
+		t.Errorf("Expected 2 matching releases, got %d", len(matching))
+	}
+	if latest := LatestMatchingRelease(releases, c); latest == nil || latest.ID != "2" {
+		t.Errorf("Expected release 2, got %v", latest)
+	}
+
+	c, _ = ParseManifestConstraint("^4.0")
+	if LatestMatchingRelease(releases, c) != nil {
+		t.Error("Expected no matching release")
+	}
+}
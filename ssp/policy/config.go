@@ -0,0 +1,170 @@
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+	"gopkg.in/yaml.v2"
+)
+
+// ruleConfig is the YAML representation of any built-in rule.
+type ruleConfig struct {
+	Type              string   `yaml:"type"`
+	Stacks            []string `yaml:"stacks"`
+	Environments      []string `yaml:"environments"`
+	Usages            []string `yaml:"usages"`
+	SourceStack       string   `yaml:"source_stack"`
+	SourceEnvironment string   `yaml:"source_environment"`
+	Within            string   `yaml:"within"`
+	Weekdays          []string `yaml:"weekdays"`
+	After             string   `yaml:"after"`
+	Before            string   `yaml:"before"`
+	Timezone          string   `yaml:"timezone"`
+}
+
+type config struct {
+	Rules []ruleConfig `yaml:"rules"`
+}
+
+// LoadFile reads rules from a YAML file, in the format described by Parse.
+func LoadFile(path string) ([]Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads rules from YAML. Each rule has a type, one of completed_on (RequireCompletedOn), block_time
+// (BlockTime) or approver_not_deployer (ApproverNotDeployer), and can be scoped with stacks, environments and
+// usages. Durations accept Go duration syntax, and a "d" suffix for days. Times of day are "15:04", and a
+// block_time window with after later than before crosses midnight. Unknown keys are rejected.
+//
+//	rules:
+//	  - type: completed_on
+//	    usages: [Production]
+//	    source_environment: uat
+//	    within: 14d
+//	  - type: block_time
+//	    weekdays: [Friday]
+//	    after: "15:00"
+//	    timezone: Pacific/Auckland
+//	  - type: approver_not_deployer
+func Parse(data []byte) ([]Rule, error) {
+	c := &config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed parsing policy: '%s'", err)
+	}
+
+	rules := make([]Rule, len(c.Rules))
+	for i, rc := range c.Rules {
+		rule, err := rc.rule()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %s", i+1, err)
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+func (rc ruleConfig) rule() (Rule, error) {
+	scope, err := rc.scope()
+	if err != nil {
+		return nil, err
+	}
+
+	switch rc.Type {
+	case "completed_on":
+		if rc.SourceEnvironment == "" {
+			return nil, fmt.Errorf("source_environment is required")
+		}
+		within, err := parseDuration(rc.Within)
+		if err != nil {
+			return nil, err
+		}
+		return &RequireCompletedOn{Scope: scope, SourceStack: rc.SourceStack, SourceEnvironment: rc.SourceEnvironment, Within: within}, nil
+
+	case "block_time":
+		r := &BlockTime{Scope: scope}
+		for _, name := range rc.Weekdays {
+			wd, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			r.Weekdays = append(r.Weekdays, wd)
+		}
+		if r.After, err = parseTimeOfDay(rc.After); err != nil {
+			return nil, err
+		}
+		if r.Before, err = parseTimeOfDay(rc.Before); err != nil {
+			return nil, err
+		}
+		if r.Location, err = time.LoadLocation(rc.Timezone); err != nil {
+			return nil, err
+		}
+		return r, nil
+
+	case "approver_not_deployer":
+		return &ApproverNotDeployer{Scope: scope}, nil
+	}
+
+	return nil, fmt.Errorf("unknown rule type '%s'", rc.Type)
+}
+
+func (rc ruleConfig) scope() (Scope, error) {
+	s := Scope{Stacks: rc.Stacks, Environments: rc.Environments}
+	usages := map[string]ssp.Usage{
+		"Production":  ssp.UsageProduction,
+		"UAT":         ssp.UsageUAT,
+		"Test":        ssp.UsageTest,
+		"Unspecified": ssp.UsageUnspecified,
+	}
+	for _, name := range rc.Usages {
+		u, ok := usages[name]
+		if !ok {
+			return s, fmt.Errorf("unknown usage '%s'", name)
+		}
+		s.Usages = append(s.Usages, u)
+	}
+	return s, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return d, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s'", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(wd.String(), s) {
+			return wd, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday '%s'", s)
+}
@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+func TestParse(t *testing.T) {
+	rules, err := Parse([]byte(`
+rules:
+  - type: completed_on
+    usages: [Production]
+    source_environment: uat
+    within: 14d
+  - type: block_time
+    weekdays: [Friday]
+    after: "15:00"
+    timezone: Pacific/Auckland
+  - type: approver_not_deployer
+    stacks: [one]
+`))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(rules))
+	}
+
+	completed := rules[0].(*RequireCompletedOn)
+	if completed.Within != 14*24*time.Hour || completed.SourceEnvironment != "uat" || completed.Usages[0] != ssp.UsageProduction {
+		t.Errorf("Unexpected rule %+v", completed)
+	}
+	block := rules[1].(*BlockTime)
+	if block.Weekdays[0] != time.Friday || block.After != 15*time.Hour || block.Location.String() != "Pacific/Auckland" {
+		t.Errorf("Unexpected rule %+v", block)
+	}
+	if rules[2].(*ApproverNotDeployer).Stacks[0] != "one" {
+		t.Error("Scope not parsed")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	invalid := []string{
+		"rules:\n  - type: unknown\n",
+		"rules:\n  - type: completed_on\n    within: 14d\n",
+		"rules:\n  - type: completed_on\n    source_environment: uat\n    within: fortnight\n",
+		"rules:\n  - type: block_time\n    weekdays: [Someday]\n",
+		"rules:\n  - type: approver_not_deployer\n    usages: [Staging]\n",
+		"rules:\n  - type: approver_not_deployer\n    colour: blue\n",
+	}
+	for _, in := range invalid {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("Expected error parsing:\n%s", in)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package policy enforces local change-management rules before deployments are approved.
+//
+// Rules are defined in code, or loaded from a YAML file with LoadFile. An Engine checks a pending deployment
+// against all rules and returns a Decision explaining which rules allowed or denied it. Wrapping the SDK client
+// with NewClient makes every ApproveDeployment call go through the engine:
+//
+//	rules, _ := policy.LoadFile("approvals.yml")
+//	c := policy.NewClient(sspClient, policy.NewEngine(rules...))
+//	d, err := c.ApproveDeployment("mystack", "prod", &ssp.ApproveDeployment{ID: 123})
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+// Input is everything a rule can check a pending deployment against. Client gives rules access to the
+// deployment history of other environments.
+type Input struct {
+	Client      *ssp.Client
+	Stack       string
+	Environment *ssp.Environment
+	Deployment  *ssp.Deployment
+	Approver    string
+	Now         time.Time
+}
+
+// Rule checks a pending deployment. A rule returns an error only if it could not be evaluated, which denies the
+// approval.
+type Rule interface {
+	Name() string
+	Check(in *Input) (*Result, error)
+}
+
+// Result is the outcome of a single rule. Skipped rules did not apply to the deployment's environment.
+type Result struct {
+	Rule    string
+	Allowed bool
+	Skipped bool
+	Reason  string
+}
+
+// Decision is the outcome of all rules. The deployment is allowed only if no rule denied it.
+type Decision struct {
+	Allowed bool
+	Results []*Result
+}
+
+// Reasons lists the reasons given by the rules that denied the deployment.
+func (d *Decision) Reasons() []string {
+	reasons := []string{}
+	for _, r := range d.Results {
+		if !r.Allowed {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", r.Rule, r.Reason))
+		}
+	}
+	return reasons
+}
+
+func (d *Decision) String() string {
+	if d.Allowed {
+		return "allowed"
+	}
+	return fmt.Sprintf("denied (%s)", strings.Join(d.Reasons(), "; "))
+}
+
+// DeniedError is returned when an approval is attempted on a deployment the policy denies.
+type DeniedError struct {
+	Decision *Decision
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("approval denied by policy: %s", strings.Join(e.Decision.Reasons(), "; "))
+}
+
+// Engine evaluates deployments against a set of rules.
+type Engine struct {
+	Rules []Rule
+	now   func() time.Time
+}
+
+// NewEngine creates an Engine checking the given rules.
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{Rules: rules}
+}
+
+// Evaluate checks the deployment dID pending on the environment, on behalf of the approver.
+func (e *Engine) Evaluate(c *ssp.Client, sID string, eID string, dID int, approver string) (*Decision, error) {
+	d, err := c.GetDeployment(sID, eID, strconv.Itoa(dID))
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching deployment: '%s'", err)
+	}
+	env, err := c.GetEnvironment(sID, eID)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching environment: '%s'", err)
+	}
+
+	now := time.Now()
+	if e.now != nil {
+		now = e.now()
+	}
+
+	return e.Check(&Input{
+		Client:      c,
+		Stack:       sID,
+		Environment: env,
+		Deployment:  d,
+		Approver:    approver,
+		Now:         now,
+	})
+}
+
+// Check evaluates all rules against an already assembled input.
+func (e *Engine) Check(in *Input) (*Decision, error) {
+	decision := &Decision{Allowed: true}
+	for _, rule := range e.Rules {
+		if s, ok := rule.(scoped); ok && !s.applies(in) {
+			decision.Results = append(decision.Results, &Result{Rule: rule.Name(), Allowed: true, Skipped: true})
+			continue
+		}
+
+		result, err := rule.Check(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed evaluating rule %s: '%s'", rule.Name(), err)
+		}
+		result.Rule = rule.Name()
+		decision.Results = append(decision.Results, result)
+		if !result.Allowed {
+			decision.Allowed = false
+		}
+	}
+
+	return decision, nil
+}
+
+// Client wraps the SDK client so that approvals cannot bypass the policy. It exposes only the operations that can
+// approve or start a deployment, each of them checked against the policy. The approver is the email of the
+// configured Dashboard account.
+type Client struct {
+	client *ssp.Client
+	Engine *Engine
+}
+
+// NewClient wraps the SDK client with the policy engine.
+func NewClient(c *ssp.Client, e *Engine) *Client {
+	return &Client{client: c, Engine: e}
+}
+
+// ApproveDeployment approves the deployment only if the policy allows it, and returns a *DeniedError otherwise.
+func (c *Client) ApproveDeployment(sID string, eID string, ad *ssp.ApproveDeployment) (*ssp.Deployment, error) {
+	decision, err := c.Engine.Evaluate(c.client, sID, eID, ad.ID, c.client.Config.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !decision.Allowed {
+		return nil, &DeniedError{Decision: decision}
+	}
+
+	return c.client.ApproveDeployment(sID, eID, ad)
+}
+
+// CreateDeployment creates the deployment. Bypass and BypassAndStart would skip the approval, so the deployment is
+// created pending approval instead, then approved, and started for BypassAndStart, only if the policy allows it.
+// A denied deployment is returned, still pending approval, along with a *DeniedError.
+func (c *Client) CreateDeployment(sID string, eID string, cd *ssp.CreateDeployment) (*ssp.Deployment, error) {
+	if !cd.Bypass && !cd.BypassAndStart {
+		return c.client.CreateDeployment(sID, eID, cd)
+	}
+
+	pending := *cd
+	pending.Bypass = false
+	pending.BypassAndStart = false
+	d, err := c.client.CreateDeployment(sID, eID, &pending)
+	if err != nil {
+		return nil, err
+	}
+
+	approved, err := c.ApproveDeployment(sID, eID, &ssp.ApproveDeployment{ID: d.ID})
+	if err != nil {
+		return d, err
+	}
+	if !cd.BypassAndStart {
+		return approved, nil
+	}
+
+	return c.client.StartDeployment(sID, eID, &ssp.StartDeployment{ID: d.ID})
+}
+
+// StartDeployment starts a deployment, which must already have been approved.
+func (c *Client) StartDeployment(sID string, eID string, sd *ssp.StartDeployment) (*ssp.Deployment, error) {
+	return c.client.StartDeployment(sID, eID, sd)
+}
@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+// friday is a Friday afternoon in Auckland.
+var friday = time.Date(2017, 3, 31, 4, 0, 0, 0, time.UTC)
+
+// newMockDashboard serves the routes, and counts the POST requests to each path. Nothing may be posted with a
+// bypass of the approval.
+func newMockDashboard(t *testing.T, routes map[string]interface{}) (*ssp.Client, *httptest.Server, map[string]int) {
+	posts := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			posts[r.URL.Path]++
+			body, _ := ioutil.ReadAll(r.Body)
+			if bytes.Contains(body, []byte(`"bypass":true`)) || bytes.Contains(body, []byte(`"bypass_and_start":true`)) {
+				t.Errorf("Approval bypassed in %s", body)
+			}
+		}
+		payload, ok := routes[r.Method+" "+r.URL.Path]
+		w.Header().Add("Content-Type", jsonapi.MediaType)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		jsonapi.MarshalPayload(w, payload)
+	}))
+
+	c, err := ssp.NewClient(&ssp.Config{BaseURL: ts.URL, Email: "approver@example.com"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return c, ts, posts
+}
+
+func policyRoutes(deployer string, uatHistory []*ssp.Deployment) map[string]interface{} {
+	return map[string]interface{}{
+		"GET /naut/project/one/environment/prod/deploys/5":          &ssp.Deployment{ID: 5, SHA: "abc", DeployerEmail: deployer, OriginalState: "Submitted"},
+		"GET /naut/project/one/environment/prod":                    &ssp.Environment{ID: "prod", OriginalUsage: "Production"},
+		"GET /naut/project/one/environment/uat/deploys":             uatHistory,
+		"POST /naut/project/one/environment/prod/approvals/approve": &ssp.Deployment{ID: 5, OriginalState: "Approved"},
+		"POST /naut/project/one/environment/prod/deploys":           &ssp.Deployment{ID: 5, OriginalState: "Submitted"},
+		"POST /naut/project/one/environment/prod/deploys/start":     &ssp.Deployment{ID: 5, OriginalState: "Queued"},
+	}
+}
+
+func testRules() []Rule {
+	auckland, _ := time.LoadLocation("Pacific/Auckland")
+	return []Rule{
+		&RequireCompletedOn{Scope: Scope{Usages: []ssp.Usage{ssp.UsageProduction}}, SourceEnvironment: "uat", Within: 14 * 24 * time.Hour},
+		&BlockTime{Weekdays: []time.Weekday{time.Friday}, After: 15 * time.Hour, Location: auckland},
+		&ApproverNotDeployer{},
+	}
+}
+
+func TestEvaluateAllowed(t *testing.T) {
+	c, ts, _ := newMockDashboard(t, policyRoutes("deployer@example.com", []*ssp.Deployment{
+		{ID: 4, SHA: "abc", OriginalState: "Completed", DateUpdated: friday.Add(-48 * time.Hour)},
+	}))
+	defer ts.Close()
+
+	e := NewEngine(testRules()...)
+	e.now = func() time.Time { return friday.Add(-24 * time.Hour) }
+
+	d, err := e.Evaluate(c, "one", "prod", 5, "approver@example.com")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !d.Allowed {
+		t.Errorf("Expected deployment to be allowed, got %s", d)
+	}
+	if len(d.Results) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(d.Results))
+	}
+}
+
+func TestEvaluateDenied(t *testing.T) {
+	c, ts, _ := newMockDashboard(t, policyRoutes("approver@example.com", []*ssp.Deployment{
+		{ID: 4, SHA: "abc", OriginalState: "Completed", DateUpdated: friday.Add(-20 * 24 * time.Hour)},
+	}))
+	defer ts.Close()
+
+	e := NewEngine(testRules()...)
+	e.now = func() time.Time { return friday }
+
+	d, err := e.Evaluate(c, "one", "prod", 5, "approver@example.com")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if d.Allowed {
+		t.Error("Expected deployment to be denied")
+	}
+	if len(d.Reasons()) != 3 {
+		t.Errorf("Expected all 3 rules to deny, got %v", d.Reasons())
+	}
+}
+
+func TestEvaluateOutOfScope(t *testing.T) {
+	c, ts, _ := newMockDashboard(t, policyRoutes("deployer@example.com", nil))
+	defer ts.Close()
+
+	e := NewEngine(&RequireCompletedOn{Scope: Scope{Environments: []string{"other"}}, SourceEnvironment: "uat", Within: time.Hour})
+	d, err := e.Evaluate(c, "one", "prod", 5, "approver@example.com")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !d.Allowed || !d.Results[0].Skipped {
+		t.Error("Rule out of scope should be skipped")
+	}
+}
+
+func TestClientApproveDeployment(t *testing.T) {
+	c, ts, posts := newMockDashboard(t, policyRoutes("approver@example.com", nil))
+	defer ts.Close()
+
+	pc := NewClient(c, NewEngine(&ApproverNotDeployer{}))
+	_, err := pc.ApproveDeployment("one", "prod", &ssp.ApproveDeployment{ID: 5})
+	if _, ok := err.(*DeniedError); !ok {
+		t.Fatalf("Expected DeniedError, got %v", err)
+	}
+	if posts[approvePath] != 0 {
+		t.Error("Denied deployment should not be approved")
+	}
+
+	c.Config.Email = "someone@example.com"
+	d, err := pc.ApproveDeployment("one", "prod", &ssp.ApproveDeployment{ID: 5})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if d.State != ssp.StateApproved || posts[approvePath] != 1 {
+		t.Error("Allowed deployment should be approved")
+	}
+}
+
+const approvePath = "/naut/project/one/environment/prod/approvals/approve"
+
+func TestClientCreateDeploymentBypass(t *testing.T) {
+	c, ts, posts := newMockDashboard(t, policyRoutes("approver@example.com", nil))
+	defer ts.Close()
+
+	pc := NewClient(c, NewEngine(&ApproverNotDeployer{}))
+	d, err := pc.CreateDeployment("one", "prod", &ssp.CreateDeployment{Ref: "master", BypassAndStart: true})
+	if _, ok := err.(*DeniedError); !ok {
+		t.Fatalf("Expected DeniedError, got %v", err)
+	}
+	if d == nil || d.ID != 5 {
+		t.Error("Denied deployment should be returned pending approval")
+	}
+	if posts[approvePath] != 0 || posts["/naut/project/one/environment/prod/deploys/start"] != 0 {
+		t.Error("Denied deployment should not be approved or started")
+	}
+
+	c.Config.Email = "someone@example.com"
+	d, err = pc.CreateDeployment("one", "prod", &ssp.CreateDeployment{Ref: "master", BypassAndStart: true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if d.State != ssp.StateQueued || posts[approvePath] != 1 || posts["/naut/project/one/environment/prod/deploys/start"] != 1 {
+		t.Error("Allowed deployment should be approved and started")
+	}
+}
+
+func TestRequireCompletedOnFiltersByCompletion(t *testing.T) {
+	var query url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Add("Content-Type", jsonapi.MediaType)
+		jsonapi.MarshalPayload(w, []*ssp.Deployment{
+			{ID: 4, SHA: "abc", OriginalState: "Completed", DateStarted: friday.Add(-48 * time.Hour), DateUpdated: friday.Add(-time.Hour)},
+		})
+	}))
+	defer ts.Close()
+	c, err := ssp.NewClient(&ssp.Config{BaseURL: ts.URL})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	rule := &RequireCompletedOn{SourceEnvironment: "uat", Within: 24 * time.Hour}
+	res, err := rule.Check(&Input{Client: c, Stack: "one", Deployment: &ssp.Deployment{SHA: "abc"}, Now: friday})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !res.Allowed {
+		t.Errorf("Deployment started before but completed within the window should be allowed: %s", res.Reason)
+	}
+	if query.Get("lastedited_from_unix") != strconv.FormatInt(friday.Add(-24*time.Hour).Unix(), 10) || query.Get("datestarted_from_unix") != "" {
+		t.Errorf("Unexpected query %v", query)
+	}
+}
+
+func TestBlockTimeOvernight(t *testing.T) {
+	rule := &BlockTime{Weekdays: []time.Weekday{time.Friday}, After: 22 * time.Hour, Before: 6 * time.Hour}
+	day := time.Date(2017, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	blocked := map[time.Duration]bool{
+		-time.Hour:       false,
+		5 * time.Hour:    false,
+		21 * time.Hour:   false,
+		23 * time.Hour:   true,
+		24*time.Hour + 1: true,
+		29 * time.Hour:   true,
+		30 * time.Hour:   false,
+	}
+	for offset, expected := range blocked {
+		now := day.Add(offset)
+		res, err := rule.Check(&Input{Now: now})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if res.Allowed == expected {
+			t.Errorf("Expected %s to be blocked: %t", now.Format("Mon 15:04"), expected)
+		}
+	}
+}
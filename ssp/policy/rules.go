@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+// Scope limits a rule to some stacks, environments or environment usages. Environments match either the ID or
+// the name of the environment. An empty list matches everything.
+type Scope struct {
+	Stacks       []string
+	Environments []string
+	Usages       []ssp.Usage
+}
+
+type scoped interface {
+	applies(in *Input) bool
+}
+
+func (s Scope) applies(in *Input) bool {
+	if len(s.Stacks) > 0 && !contains(s.Stacks, in.Stack) {
+		return false
+	}
+	if len(s.Environments) > 0 && !contains(s.Environments, in.Environment.ID) && !contains(s.Environments, in.Environment.Name) {
+		return false
+	}
+	if len(s.Usages) > 0 {
+		for _, u := range s.Usages {
+			if u == in.Environment.Usage {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// RequireCompletedOn allows only SHAs that completed on the source environment within the given time, for
+// example "Production only gets SHAs that have completed on UAT in the last 14 days". SourceStack defaults to
+// the stack of the deployment.
+type RequireCompletedOn struct {
+	Scope
+	SourceStack       string
+	SourceEnvironment string
+	Within            time.Duration
+}
+
+func (r *RequireCompletedOn) Name() string {
+	return fmt.Sprintf("completed on %s within %s", r.SourceEnvironment, r.Within)
+}
+
+func (r *RequireCompletedOn) Check(in *Input) (*Result, error) {
+	stack := r.SourceStack
+	if stack == "" {
+		stack = in.Stack
+	}
+	since := in.Now.Add(-r.Within)
+
+	deployments, err := in.Client.ListDeployments(stack, r.SourceEnvironment, &ssp.DeploymentFilter{
+		States:         []ssp.State{ssp.StateCompleted},
+		SHA:            in.Deployment.SHA,
+		LastEditedFrom: since,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range deployments {
+		if d.State == ssp.StateCompleted && d.SHA == in.Deployment.SHA && !d.DateUpdated.Before(since) {
+			return &Result{Allowed: true, Reason: fmt.Sprintf("completed on %s/%s in deployment #%d", stack, r.SourceEnvironment, d.ID)}, nil
+		}
+	}
+
+	return &Result{Reason: fmt.Sprintf("%s has not completed on %s/%s since %s", in.Deployment.SHA, stack, r.SourceEnvironment, since.Format(time.RFC3339))}, nil
+}
+
+// BlockTime denies approvals on the given weekdays between After and Before, for example "no approvals on
+// Fridays after 3pm". After and Before are offsets from midnight in Location, and Before defaults to the end
+// of the day. No weekdays means every day. If After is later than Before the window crosses midnight, and the
+// hours after midnight belong to the weekday the window started on.
+type BlockTime struct {
+	Scope
+	Weekdays []time.Weekday
+	After    time.Duration
+	Before   time.Duration
+	Location *time.Location
+}
+
+func (r *BlockTime) Name() string {
+	return "blocked time"
+}
+
+func (r *BlockTime) Check(in *Input) (*Result, error) {
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := in.Now.In(loc)
+
+	before := r.Before
+	if before <= 0 {
+		before = 24 * time.Hour
+	}
+	sinceMidnight := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+
+	day := now.Weekday()
+	blocked := sinceMidnight >= r.After && sinceMidnight < before
+	if r.After >= before {
+		blocked = sinceMidnight >= r.After || sinceMidnight < before
+		if sinceMidnight < before {
+			day = (day + 6) % 7
+		}
+	}
+	if blocked && r.blocksOn(day) {
+		return &Result{Reason: fmt.Sprintf("approvals are blocked on %s at %s", now.Weekday(), now.Format("15:04 MST"))}, nil
+	}
+
+	return &Result{Allowed: true}, nil
+}
+
+func (r *BlockTime) blocksOn(day time.Weekday) bool {
+	if len(r.Weekdays) == 0 {
+		return true
+	}
+	for _, wd := range r.Weekdays {
+		if wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ApproverNotDeployer denies approvals by the person who requested the deployment. The approval is denied if
+// either of them is unknown.
+type ApproverNotDeployer struct {
+	Scope
+}
+
+func (r *ApproverNotDeployer) Name() string {
+	return "approver is not deployer"
+}
+
+func (r *ApproverNotDeployer) Check(in *Input) (*Result, error) {
+	if in.Approver == "" || in.Deployment.DeployerEmail == "" {
+		return &Result{Reason: "approver or deployer is unknown"}, nil
+	}
+	if strings.EqualFold(in.Approver, in.Deployment.DeployerEmail) {
+		return &Result{Reason: fmt.Sprintf("%s cannot approve their own deployment", in.Approver)}, nil
+	}
+	return &Result{Allowed: true}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package ssp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSourceDeploying is returned when promoting from an environment that is in the middle of a deployment.
+var ErrSourceDeploying = errors.New("source environment is being deployed")
+
+// PromoteDeployment configures a promotion of the build currently live on the source environment.
+//
+// SourceStack defaults to the target stack when left empty.
+type PromoteDeployment struct {
+	SourceStack       string
+	SourceEnvironment string
+	Options           []DeploymentOption
+	Bypass            bool
+	BypassAndStart    bool
+	Locked            bool
+}
+
+// Promotion describes a promotion: the deployment live on the source environment, the request sent to the
+// target environment, and the resulting deployment.
+type Promotion struct {
+	Source     *Deployment
+	Request    *CreateDeployment
+	Deployment *Deployment
+}
+
+// PromoteDeployment deploys the SHA currently live on the source environment onto the target environment
+// identified by sID and eID. The source deployment must have completed successfully.
+func (a *Client) PromoteDeployment(sID string, eID string, pd *PromoteDeployment) (*Promotion, error) {
+	if pd == nil || pd.SourceEnvironment == "" {
+		return nil, errors.New("source environment is required")
+	}
+
+	sourceStack := pd.SourceStack
+	if sourceStack == "" {
+		sourceStack = sID
+	}
+	if sourceStack == sID && pd.SourceEnvironment == eID {
+		return nil, errors.New("source and target environment are the same")
+	}
+
+	source, err := a.GetDeploymentCurrent(sourceStack, pd.SourceEnvironment)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching current deployment of %s/%s: '%s'", sourceStack, pd.SourceEnvironment, err)
+	}
+	if source.State.InProgress() {
+		return nil, ErrSourceDeploying
+	}
+	if source.State != StateCompleted {
+		return nil, fmt.Errorf("source deployment #%d is '%s', expected '%s'", source.ID, source.OriginalState, StateCompleted)
+	}
+	if source.SHA == "" {
+		return nil, fmt.Errorf("source deployment #%d has no SHA", source.ID)
+	}
+
+	p := &Promotion{
+		Source: source,
+		Request: &CreateDeployment{
+			Ref:            source.SHA,
+			RefType:        RefTypeSHA,
+			Title:          fmt.Sprintf("Promote %s from %s/%s", shortSHA(source), sourceStack, pd.SourceEnvironment),
+			Summary:        fmt.Sprintf("Promoted from deployment #%d on %s/%s.", source.ID, sourceStack, pd.SourceEnvironment),
+			Options:        pd.Options,
+			Bypass:         pd.Bypass,
+			BypassAndStart: pd.BypassAndStart,
+			Locked:         pd.Locked,
+		},
+	}
+
+	p.Deployment, err = a.CreateDeployment(sID, eID, p.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating promoted deployment: '%s'", err)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,66 @@
+package ssp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPromoteDeployment(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/other/environment/uat/deploys/current": {http.StatusOK, &Deployment{ID: 7, SHA: "abcdef0123", OriginalState: "Completed"}},
+		"POST /naut/project/one/environment/prod/deploys":         {http.StatusCreated, &Deployment{ID: 8}},
+	})
+	defer ts.Close()
+
+	p, err := api.PromoteDeployment("one", "prod", &PromoteDeployment{SourceStack: "other", SourceEnvironment: "uat"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Deployment.ID != 8 {
+		t.Error("Promoted deployment not returned")
+	}
+
+	posts := m.requestsTo("POST", "/naut/project/one/environment/prod/deploys")
+	if len(posts) != 1 {
+		t.Fatalf("Expected one deployment to be created, got %d", len(posts))
+	}
+	cd := &CreateDeployment{}
+	if err := json.Unmarshal(posts[0].body, cd); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if cd.Ref != "abcdef0123" || cd.RefType != RefTypeSHA {
+		t.Errorf("Unexpected ref '%s' of type '%s'", cd.Ref, cd.RefType)
+	}
+	if !strings.Contains(cd.Summary, "#7") {
+		t.Errorf("Summary does not reference the source deployment: '%s'", cd.Summary)
+	}
+}
+
+func TestPromoteDeploymentSourceDeploying(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/uat/deploys/current": {http.StatusOK, &Deployment{ID: 7, SHA: "abcdef0123", OriginalState: "Deploying"}},
+	})
+	defer ts.Close()
+
+	_, err := api.PromoteDeployment("one", "prod", &PromoteDeployment{SourceEnvironment: "uat"})
+	if err != ErrSourceDeploying {
+		t.Errorf("Expected ErrSourceDeploying, got %v", err)
+	}
+	if len(m.requestsTo("POST", "/naut/project/one/environment/prod/deploys")) != 0 {
+		t.Error("Deployment should not be created")
+	}
+}
+
+func TestPromoteDeploymentSourceFailed(t *testing.T) {
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/uat/deploys/current": {http.StatusOK, &Deployment{ID: 7, SHA: "abcdef0123", OriginalState: "Failed"}},
+	})
+	defer ts.Close()
+
+	_, err := api.PromoteDeployment("one", "prod", &PromoteDeployment{SourceEnvironment: "uat"})
+	if err == nil {
+		t.Error("Expected error when promoting a failed deployment")
+	}
+}
@@ -0,0 +1,107 @@
+package ssp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const queueConcurrency = 8
+
+// QueueEntry is a deployment waiting for approval or to be deployed, with the stack and environment it targets.
+type QueueEntry struct {
+	Stack       *Stack
+	Environment *Environment
+	Deployment  *Deployment
+}
+
+// NeedsApproval reports whether the deployment has been submitted and is waiting for an approval.
+func (e *QueueEntry) NeedsApproval() bool {
+	return e.Deployment.State == StateSubmitted
+}
+
+// DeploymentQueue lists the pending deployments across all visible stacks, oldest request first.
+//
+// Environments that could not be listed are reported in Errors rather than failing the whole queue.
+type DeploymentQueue struct {
+	Entries []*QueueEntry
+	Errors  []error
+}
+
+// NeedingApproval returns the queue entries waiting for an approval.
+func (q *DeploymentQueue) NeedingApproval() []*QueueEntry {
+	entries := []*QueueEntry{}
+	for _, e := range q.Entries {
+		if e.NeedsApproval() {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// ListDeploymentQueue collects all Submitted, Approved and Queued deployments across all stacks and environments
+// visible to the current user.
+func (a *Client) ListDeploymentQueue() (*DeploymentQueue, error) {
+	stacks, err := a.ListStacks()
+	if err != nil {
+		return nil, err
+	}
+
+	type job struct {
+		stack *Stack
+		env   *Environment
+	}
+	jobs := make(chan job)
+	q := &DeploymentQueue{
+		Entries: []*QueueEntry{},
+	}
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	filter := &DeploymentFilter{States: []State{StateSubmitted, StateApproved, StateQueued}}
+	for i := 0; i < queueConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				deployments, err := a.ListDeployments(j.stack.ID, j.env.ID, filter)
+
+				mu.Lock()
+				if err != nil {
+					q.Errors = append(q.Errors, fmt.Errorf("failed listing deployments of %s/%s: '%s'", j.stack.ID, j.env.ID, err))
+				}
+				for _, d := range deployments {
+					if d.State != StateSubmitted && d.State != StateApproved && d.State != StateQueued {
+						continue
+					}
+					q.Entries = append(q.Entries, &QueueEntry{Stack: j.stack, Environment: j.env, Deployment: d})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, s := range stacks {
+		for _, e := range s.Environments {
+			jobs <- job{stack: s, env: e}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(q.Entries, func(i, j int) bool {
+		x, y := q.Entries[i], q.Entries[j]
+		if !x.Deployment.DateRequested.Equal(y.Deployment.DateRequested) {
+			return x.Deployment.DateRequested.Before(y.Deployment.DateRequested)
+		}
+		if x.Stack.ID != y.Stack.ID {
+			return x.Stack.ID < y.Stack.ID
+		}
+		if x.Environment.ID != y.Environment.ID {
+			return x.Environment.ID < y.Environment.ID
+		}
+		return x.Deployment.ID < y.Deployment.ID
+	})
+
+	return q, nil
+}
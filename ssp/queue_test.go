@@ -0,0 +1,47 @@
+package ssp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListDeploymentQueue(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/projects": {http.StatusOK, []*Stack{
+			{ID: "one", Environments: []*Environment{{ID: "uat"}, {ID: "prod"}}},
+			{ID: "two", Environments: []*Environment{{ID: "prod"}}},
+		}},
+		"GET /naut/project/one/environment/uat/deploys": {http.StatusOK, []*Deployment{
+			{ID: 1, OriginalState: "Queued", DateRequested: now.Add(-time.Minute)},
+		}},
+		"GET /naut/project/one/environment/prod/deploys": {http.StatusOK, []*Deployment{
+			{ID: 2, OriginalState: "Submitted", DateRequested: now.Add(-time.Hour)},
+			{ID: 3, OriginalState: "Completed", DateRequested: now.Add(-2 * time.Hour)},
+		}},
+	})
+	defer ts.Close()
+
+	q, err := api.ListDeploymentQueue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(q.Entries) != 2 {
+		t.Fatalf("Expected 2 queue entries, got %d", len(q.Entries))
+	}
+	if q.Entries[0].Deployment.ID != 2 || q.Entries[0].Stack.ID != "one" || q.Entries[0].Environment.ID != "prod" {
+		t.Errorf("Unexpected first entry %d on %s/%s", q.Entries[0].Deployment.ID, q.Entries[0].Stack.ID, q.Entries[0].Environment.ID)
+	}
+	if len(q.NeedingApproval()) != 1 {
+		t.Errorf("Expected 1 entry needing approval, got %d", len(q.NeedingApproval()))
+	}
+	if len(q.Errors) != 1 {
+		t.Errorf("Expected the unavailable environment to be reported, got %v", q.Errors)
+	}
+
+	reqs := m.requestsTo("GET", "/naut/project/one/environment/prod/deploys")
+	if len(reqs) != 1 || reqs[0].query.Get("state") != "Submitted,Approved,Queued" {
+		t.Error("Deployments not filtered by state")
+	}
+}
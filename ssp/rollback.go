@@ -0,0 +1,104 @@
+package ssp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNoRollbackTarget is returned when there is no earlier successful deployment to roll back to.
+var ErrNoRollbackTarget = errors.New("no previous completed deployment to roll back to")
+
+// RollbackDeployment configures a rollback of an environment to its last known-good deployment.
+//
+// With DryRun set, the rollback target is resolved but no deployment is created.
+type RollbackDeployment struct {
+	DryRun         bool
+	Options        []DeploymentOption
+	Bypass         bool
+	BypassAndStart bool
+	Locked         bool
+}
+
+// Rollback describes a rollback: the deployment currently live, the deployment being redeployed,
+// and the request sent (or that would be sent on a dry run) to the Dashboard.
+type Rollback struct {
+	Current    *Deployment
+	Target     *Deployment
+	Request    *CreateDeployment
+	Deployment *Deployment
+}
+
+// RollbackDeployment redeploys the SHA of the most recent completed deployment preceding the current one.
+// Failed deployments and deployments of the currently live SHA are skipped.
+func (a *Client) RollbackDeployment(sID string, eID string, rd *RollbackDeployment) (*Rollback, error) {
+	if rd == nil {
+		rd = &RollbackDeployment{}
+	}
+
+	current, err := a.GetDeploymentCurrent(sID, eID)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching current deployment: '%s'", err)
+	}
+
+	completed, err := a.ListDeployments(sID, eID, &DeploymentFilter{States: []State{StateCompleted}})
+	if err != nil {
+		return nil, fmt.Errorf("failed listing deployments: '%s'", err)
+	}
+
+	target := findRollbackTarget(current, completed)
+	if target == nil {
+		return nil, ErrNoRollbackTarget
+	}
+
+	rb := &Rollback{
+		Current: current,
+		Target:  target,
+		Request: &CreateDeployment{
+			Ref:            target.SHA,
+			RefType:        RefTypeSHA,
+			Title:          fmt.Sprintf("Rollback to %s", shortSHA(target)),
+			Summary:        fmt.Sprintf("Rollback from deployment #%d (%s) to deployment #%d (%s).", current.ID, shortSHA(current), target.ID, shortSHA(target)),
+			Options:        rd.Options,
+			Bypass:         rd.Bypass,
+			BypassAndStart: rd.BypassAndStart,
+			Locked:         rd.Locked,
+		},
+	}
+
+	if rd.DryRun {
+		return rb, nil
+	}
+
+	rb.Deployment, err = a.CreateDeployment(sID, eID, rb.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating rollback deployment: '%s'", err)
+	}
+
+	return rb, nil
+}
+
+func findRollbackTarget(current *Deployment, deployments []*Deployment) *Deployment {
+	candidates := make([]*Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		if d.State != StateCompleted || d.ID == current.ID || d.SHA == "" || d.SHA == current.SHA {
+			continue
+		}
+		if !current.DateStarted.IsZero() && d.DateStarted.After(current.DateStarted) {
+			continue
+		}
+		candidates = append(candidates, d)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].DateStarted.Equal(candidates[j].DateStarted) {
+			return candidates[i].ID > candidates[j].ID
+		}
+		return candidates[i].DateStarted.After(candidates[j].DateStarted)
+	})
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0]
+}
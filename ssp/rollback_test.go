@@ -0,0 +1,87 @@
+package ssp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func rollbackRoutes(history []*Deployment) map[string]mockRoute {
+	return map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/deploys/current": {http.StatusOK, history[0]},
+		"GET /naut/project/one/environment/prod/deploys":         {http.StatusOK, history},
+		"POST /naut/project/one/environment/prod/deploys":        {http.StatusCreated, &Deployment{ID: 99}},
+	}
+}
+
+func rollbackHistory() []*Deployment {
+	now := time.Now().Truncate(time.Second)
+	return []*Deployment{
+		{ID: 14, SHA: "cccccccccc", OriginalState: "Completed", DateStarted: now},
+		{ID: 13, SHA: "bbbbbbbbbb", OriginalState: "Failed", DateStarted: now.Add(-1 * time.Hour)},
+		{ID: 12, SHA: "cccccccccc", OriginalState: "Completed", DateStarted: now.Add(-2 * time.Hour)},
+		{ID: 11, SHA: "aaaaaaaaaa", OriginalState: "Completed", DateStarted: now.Add(-3 * time.Hour)},
+		{ID: 10, SHA: "9999999999", OriginalState: "Completed", DateStarted: now.Add(-4 * time.Hour)},
+	}
+}
+
+func TestRollbackDeployment(t *testing.T) {
+	api, ts, m := newMockRouter(rollbackRoutes(rollbackHistory()))
+	defer ts.Close()
+
+	rb, err := api.RollbackDeployment("one", "prod", nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rb.Target.ID != 11 {
+		t.Errorf("Expected rollback to deployment 11, got %d", rb.Target.ID)
+	}
+	if rb.Deployment == nil || rb.Deployment.ID != 99 {
+		t.Error("Rollback deployment not created")
+	}
+
+	posts := m.requestsTo("POST", "/naut/project/one/environment/prod/deploys")
+	if len(posts) != 1 {
+		t.Fatalf("Expected one deployment to be created, got %d", len(posts))
+	}
+	cd := &CreateDeployment{}
+	if err := json.Unmarshal(posts[0].body, cd); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if cd.Ref != "aaaaaaaaaa" || cd.RefType != RefTypeSHA {
+		t.Errorf("Unexpected ref '%s' of type '%s'", cd.Ref, cd.RefType)
+	}
+	if cd.Title != "Rollback to aaaaaaa" {
+		t.Errorf("Unexpected title '%s'", cd.Title)
+	}
+}
+
+func TestRollbackDeploymentDryRun(t *testing.T) {
+	api, ts, m := newMockRouter(rollbackRoutes(rollbackHistory()))
+	defer ts.Close()
+
+	rb, err := api.RollbackDeployment("one", "prod", &RollbackDeployment{DryRun: true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rb.Request.Ref != "aaaaaaaaaa" {
+		t.Errorf("Unexpected ref '%s'", rb.Request.Ref)
+	}
+	if rb.Deployment != nil {
+		t.Error("Dry run should not return a deployment")
+	}
+	if len(m.requestsTo("POST", "/naut/project/one/environment/prod/deploys")) != 0 {
+		t.Error("Dry run should not create a deployment")
+	}
+}
+
+func TestRollbackDeploymentNoTarget(t *testing.T) {
+	api, ts, _ := newMockRouter(rollbackRoutes(rollbackHistory()[:3]))
+	defer ts.Close()
+
+	_, err := api.RollbackDeployment("one", "prod", nil)
+	if err != ErrNoRollbackTarget {
+		t.Errorf("Expected ErrNoRollbackTarget, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package ssp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMaintenanceUnspecified is returned when scheduling against an environment without a maintenance window.
+var ErrMaintenanceUnspecified = errors.New("environment has no maintenance window")
+
+// NextMaintenanceWindow returns the first maintenance window of the environment that ends after the given time.
+// See MaintenanceWindow.Next.
+func (e *Environment) NextMaintenanceWindow(after time.Time) (time.Time, time.Time, error) {
+	return e.MaintenanceWindow.Next(after)
+}
+
+// CheckSchedule verifies that the start and end both fall within a single maintenance window of the environment.
+func (e *Environment) CheckSchedule(start time.Time, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("schedule end %s is before start %s", end, start)
+	}
+
+	wStart, wEnd, err := e.NextMaintenanceWindow(start)
+	if err != nil {
+		return err
+	}
+	if start.Before(wStart) || end.After(wEnd) {
+		return fmt.Errorf("schedule %s - %s does not fit the maintenance window %s - %s", start, end, wStart, wEnd)
+	}
+
+	return nil
+}
+
+// ScheduleInMaintenanceWindow schedules the deployment into the next maintenance window of the environment.
+// If now falls inside a window, the deployment is scheduled from the next full minute until the end of it.
+func (cd *CreateDeployment) ScheduleInMaintenanceWindow(env *Environment, now time.Time) error {
+	start, end, err := env.NextMaintenanceWindow(now)
+	if err != nil {
+		return err
+	}
+
+	if !start.After(now) {
+		start = now.Truncate(time.Minute).Add(time.Minute)
+		if !start.Before(end) {
+			start, end, err = env.NextMaintenanceWindow(end)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cd.ScheduleStart = start.Unix()
+	cd.ScheduleEnd = end.Unix()
+	return nil
+}
+
+func (cd *CreateDeployment) validateSchedule(now time.Time) error {
+	if cd.ScheduleStart != 0 && cd.ScheduleStart < now.Unix() {
+		return fmt.Errorf("schedule start %s is in the past", time.Unix(cd.ScheduleStart, 0))
+	}
+	if cd.ScheduleEnd != 0 && cd.ScheduleEnd < now.Unix() {
+		return fmt.Errorf("schedule end %s is in the past", time.Unix(cd.ScheduleEnd, 0))
+	}
+	if cd.ScheduleEnd != 0 && cd.ScheduleEnd < cd.ScheduleStart {
+		return fmt.Errorf("schedule end %s is before start %s", time.Unix(cd.ScheduleEnd, 0), time.Unix(cd.ScheduleStart, 0))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package ssp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func maintenanceEnv(t *testing.T, tz string, day time.Weekday, at string, duration time.Duration) *Environment {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	parsed, err := parseSSTime(at)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return &Environment{
+		MaintenanceWindow: MaintenanceWindow{
+			Day:      day,
+			Start:    sinceMidnight(parsed),
+			Duration: duration,
+			Location: loc,
+		},
+	}
+}
+
+func TestNextMaintenanceWindowDST(t *testing.T) {
+	env := maintenanceEnv(t, "Europe/Berlin", time.Monday, "02:00", time.Hour)
+
+	// Berlin switches to summer time on Sunday 26 March 2017.
+	before := time.Date(2017, 3, 15, 12, 0, 0, 0, time.UTC)
+	start, _, err := env.NextMaintenanceWindow(before)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !start.Equal(time.Date(2017, 3, 20, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected window start %s", start.UTC())
+	}
+
+	after := time.Date(2017, 3, 24, 12, 0, 0, 0, time.UTC)
+	start, end, err := env.NextMaintenanceWindow(after)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !start.Equal(time.Date(2017, 3, 27, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected window start %s", start.UTC())
+	}
+	if end.Sub(start) != time.Hour {
+		t.Errorf("Unexpected window length %s", end.Sub(start))
+	}
+}
+
+func TestNextMaintenanceWindowAcrossMidnight(t *testing.T) {
+	env := maintenanceEnv(t, "UTC", time.Sunday, "23:00", 3*time.Hour)
+
+	// Monday 2017-03-27 01:00 is inside the window started on Sunday.
+	now := time.Date(2017, 3, 27, 1, 0, 0, 0, time.UTC)
+	start, end, err := env.NextMaintenanceWindow(now)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !start.Equal(time.Date(2017, 3, 26, 23, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2017, 3, 27, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected window %s - %s", start, end)
+	}
+
+	cd := &CreateDeployment{}
+	if err := cd.ScheduleInMaintenanceWindow(env, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if cd.ScheduleStart != now.Add(time.Minute).Unix() || cd.ScheduleEnd != end.Unix() {
+		t.Errorf("Unexpected schedule %d - %d", cd.ScheduleStart, cd.ScheduleEnd)
+	}
+}
+
+func TestNextMaintenanceWindowUnspecified(t *testing.T) {
+	env := &Environment{}
+	_, _, err := env.NextMaintenanceWindow(time.Now())
+	if err != ErrMaintenanceUnspecified {
+		t.Errorf("Expected ErrMaintenanceUnspecified, got %v", err)
+	}
+}
+
+func TestCheckSchedule(t *testing.T) {
+	env := maintenanceEnv(t, "Pacific/Auckland", time.Wednesday, "22:00", 2*time.Hour)
+	loc := env.MaintenanceWindow.Location
+
+	inside := time.Date(2017, 3, 29, 22, 30, 0, 0, loc)
+	if err := env.CheckSchedule(inside, inside.Add(time.Hour)); err != nil {
+		t.Errorf("%s", err)
+	}
+	if err := env.CheckSchedule(inside, inside.Add(2*time.Hour)); err == nil {
+		t.Error("Schedule overrunning the window should not fit")
+	}
+	if err := env.CheckSchedule(inside.Add(-time.Hour), inside); err == nil {
+		t.Error("Schedule starting before the window should not fit")
+	}
+	if err := env.CheckSchedule(inside, inside.Add(-time.Minute)); err == nil {
+		t.Error("Schedule ending before it starts should be rejected")
+	}
+}
+
+func TestCreateDeploymentInvalidSchedule(t *testing.T) {
+	api, ts := newMockDashboard(&Deployment{}, http.StatusCreated)
+	defer ts.Close()
+
+	now := time.Now()
+	_, err := api.CreateDeployment("one", "prod", &CreateDeployment{
+		ScheduleStart: now.Add(-time.Hour).Unix(),
+	})
+	if err == nil {
+		t.Error("Expected error for schedule in the past")
+	}
+
+	_, err = api.CreateDeployment("one", "prod", &CreateDeployment{
+		ScheduleStart: now.Add(2 * time.Hour).Unix(),
+		ScheduleEnd:   now.Add(time.Hour).Unix(),
+	})
+	if err == nil {
+		t.Error("Expected error for schedule ending before start")
+	}
+
+	if _, err := api.CreateDeployment("one", "prod", nil); err == nil {
+		t.Error("Expected error without a deployment")
+	}
+}
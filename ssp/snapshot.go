@@ -0,0 +1,429 @@
+package ssp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+var (
+	// ErrChecksumMismatch is returned when a downloaded snapshot does not match the checksum reported by the
+	// Dashboard.
+	ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+	// ErrNoChecksum is returned when downloading a snapshot the Dashboard reports no checksum for.
+	ErrNoChecksum = errors.New("snapshot has no checksum to verify")
+)
+
+type SnapshotMode string
+
+const (
+	SnapshotDatabase SnapshotMode = "db"
+	SnapshotAssets   SnapshotMode = "assets"
+	SnapshotAll      SnapshotMode = "all"
+)
+
+var snapshotModes = map[string]SnapshotMode{
+	"db":     SnapshotDatabase,
+	"assets": SnapshotAssets,
+	"all":    SnapshotAll,
+}
+
+type TransferState string
+
+const (
+	TransferQueued   TransferState = "Queued"
+	TransferStarted  TransferState = "Started"
+	TransferFinished TransferState = "Finished"
+	TransferFailed   TransferState = "Failed"
+)
+
+var transferStates = map[string]TransferState{
+	"Queued":   TransferQueued,
+	"Started":  TransferStarted,
+	"Finished": TransferFinished,
+	"Failed":   TransferFailed,
+}
+
+// Final reports whether the transfer has stopped, successfully or not.
+func (s TransferState) Final() bool {
+	return s == TransferFinished || s == TransferFailed
+}
+
+// Snapshot is a stored database and/or assets archive of an environment. Checksum is the hex SHA-256 of the
+// archive, and Size its length in bytes.
+type Snapshot struct {
+	ID           string `jsonapi:"primary,snapshots"`
+	Mode         SnapshotMode
+	Size         int64        `jsonapi:"attr,size"`
+	Checksum     string       `jsonapi:"attr,checksum"`
+	Created      time.Time    `jsonapi:"attr,created_unix"`
+	Origin       string       `jsonapi:"attr,origin"`
+	Environment  *Environment `jsonapi:"relation,environment"`
+	OriginalMode string       `jsonapi:"attr,mode"`
+}
+
+// SnapshotTransfer tracks an asynchronous snapshot operation: creating, uploading or restoring a snapshot.
+type SnapshotTransfer struct {
+	ID            string `jsonapi:"primary,snapshot_transfers"`
+	Direction     string `jsonapi:"attr,direction"`
+	Mode          SnapshotMode
+	State         TransferState
+	Message       string       `jsonapi:"attr,message"`
+	Snapshot      *Snapshot    `jsonapi:"relation,snapshot"`
+	Environment   *Environment `jsonapi:"relation,environment"`
+	OriginalMode  string       `jsonapi:"attr,mode"`
+	OriginalState string       `jsonapi:"attr,status"`
+}
+
+// CreateSnapshot selects the environment to snapshot and what to include.
+type CreateSnapshot struct {
+	Environment string       `json:"environment"`
+	Mode        SnapshotMode `json:"mode"`
+}
+
+// RestoreSnapshot selects the environment to restore a snapshot into. Mode can restore only part of a snapshot,
+// and defaults to the mode of the snapshot.
+type RestoreSnapshot struct {
+	Environment string       `json:"environment"`
+	Mode        SnapshotMode `json:"mode,omitempty"`
+}
+
+// UploadSnapshot describes a local snapshot archive being uploaded to the stack.
+type UploadSnapshot struct {
+	Environment string       `url:"environment"`
+	Mode        SnapshotMode `url:"mode"`
+	Checksum    string       `url:"checksum"`
+	Size        int64        `url:"size"`
+}
+
+func validateSnapshotMode(mode SnapshotMode) error {
+	if _, ok := snapshotModes[string(mode)]; !ok {
+		return fmt.Errorf("unknown snapshot mode '%s'", mode)
+	}
+	return nil
+}
+
+// CreateSnapshot starts a snapshot of the environment. Use WaitForSnapshotTransfer to wait for the snapshot.
+func (a *Client) CreateSnapshot(sID string, cs *CreateSnapshot) (*SnapshotTransfer, error) {
+	if cs.Environment == "" {
+		return nil, errors.New("environment is required")
+	}
+	if err := validateSnapshotMode(cs.Mode); err != nil {
+		return nil, err
+	}
+
+	req, err := json.Marshal(cs)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("naut/project/%s/snapshots", sID)
+	r, err := a.post(url, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return responseToSnapshotTransfer(r)
+}
+
+func (a *Client) ListSnapshots(sID string) ([]*Snapshot, error) {
+	url := fmt.Sprintf("naut/project/%s/snapshots", sID)
+	r, err := a.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	items, err := jsonapi.UnmarshalManyPayload(r, reflect.TypeOf(new(Snapshot)))
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling snapshots: '%s'", err)
+	}
+
+	snapshots := make([]*Snapshot, len(items))
+	for i, item := range items {
+		snapshots[i] = item.(*Snapshot)
+		postProcessSnapshot(snapshots[i])
+	}
+
+	return snapshots, nil
+}
+
+func (a *Client) GetSnapshot(sID string, snapID string) (*Snapshot, error) {
+	url := fmt.Sprintf("naut/project/%s/snapshots/%s", sID, snapID)
+	r, err := a.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	snapshot := new(Snapshot)
+	err = jsonapi.UnmarshalPayload(r, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling snapshot: '%s'", err)
+	}
+	postProcessSnapshot(snapshot)
+
+	return snapshot, nil
+}
+
+func (a *Client) DeleteSnapshot(sID string, snapID string) error {
+	url := fmt.Sprintf("naut/project/%s/snapshots/%s", sID, snapID)
+	r, err := a.delete(url, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return nil
+}
+
+func (a *Client) GetSnapshotTransfer(sID string, tID string) (*SnapshotTransfer, error) {
+	url := fmt.Sprintf("naut/project/%s/snapshots/transfer/%s", sID, tID)
+	r, err := a.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return responseToSnapshotTransfer(r)
+}
+
+// WaitForSnapshotTransfer polls the transfer every interval until it finishes or fails, or the context is done.
+// A failed transfer is returned together with an error. A non-positive interval means DefaultPollInterval.
+func (a *Client) WaitForSnapshotTransfer(ctx context.Context, sID string, tID string, interval time.Duration) (*SnapshotTransfer, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		t, err := a.GetSnapshotTransfer(sID, tID)
+		if err != nil {
+			return nil, err
+		}
+		if t.State == TransferFailed {
+			return t, fmt.Errorf("snapshot transfer %s failed: '%s'", t.ID, t.Message)
+		}
+		if t.State.Final() {
+			return t, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return t, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RestoreSnapshot starts restoring the snapshot into an environment of the same stack. Use
+// WaitForSnapshotTransfer to wait for the restore.
+func (a *Client) RestoreSnapshot(sID string, snapID string, rs *RestoreSnapshot) (*SnapshotTransfer, error) {
+	if rs.Environment == "" {
+		return nil, errors.New("environment is required")
+	}
+	if rs.Mode != "" {
+		if err := validateSnapshotMode(rs.Mode); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := json.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("naut/project/%s/snapshots/%s/restore", sID, snapID)
+	r, err := a.post(url, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return responseToSnapshotTransfer(r)
+}
+
+// DownloadSnapshot streams the snapshot into the file at path, and returns the snapshot metadata.
+//
+// The download is written to path + ".part" first. If a partial file already exists, the download resumes from
+// its end, and a partial file that already matches the checksum is not downloaded again. The file is moved to path
+// only once its SHA-256 matches the snapshot checksum. On a mismatch the partial file is removed and
+// ErrChecksumMismatch is returned. Snapshots without a checksum cannot be verified, and return ErrNoChecksum
+// without downloading anything.
+func (a *Client) DownloadSnapshot(sID string, snapID string, path string) (*Snapshot, error) {
+	snapshot, err := a.GetSnapshot(sID, snapID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Checksum == "" {
+		return nil, ErrNoChecksum
+	}
+
+	part := path + ".part"
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Hash what has been downloaded so far, which also moves the offset to the end of the file.
+	h := sha256.New()
+	offset, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// A complete partial file is not requested again, as the server would answer an empty range with HTTP 416.
+	// One that is already full-length but does not match the checksum is downloaded again from the start.
+	complete := offset > 0 && hex.EncodeToString(h.Sum(nil)) == snapshot.Checksum
+	if !complete && snapshot.Size > 0 && offset >= snapshot.Size {
+		offset = 0
+	}
+	if !complete {
+		header := http.Header{}
+		if offset > 0 {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		url := fmt.Sprintf("naut/project/%s/snapshots/%s/download", sID, snapID)
+		resp, err := a.stream("GET", url, nil, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed downloading snapshot: '%s'", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusPartialContent {
+			if err := checkContentRange(resp.Header.Get("Content-Range"), offset, snapshot.Size); err != nil {
+				return nil, fmt.Errorf("failed downloading snapshot: '%s'", err)
+			}
+		} else {
+			// The server ignored the range, so start over.
+			offset = 0
+		}
+		if offset == 0 {
+			h.Reset()
+			if err := f.Truncate(0); err != nil {
+				return nil, err
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+			return nil, fmt.Errorf("failed downloading snapshot: '%s'", err)
+		}
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != snapshot.Checksum {
+		f.Close()
+		os.Remove(part)
+		return nil, ErrChecksumMismatch
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(part, path); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// checkContentRange verifies that a partial response continues the download at offset. The total length is only
+// checked if size is known.
+func checkContentRange(contentRange string, offset int64, size int64) error {
+	var first, last int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &first, &last, &total); err != nil {
+		return fmt.Errorf("invalid Content-Range '%s'", contentRange)
+	}
+	if first != offset {
+		return fmt.Errorf("Content-Range '%s' does not start at %d", contentRange, offset)
+	}
+	if size > 0 && total != "*" && total != strconv.FormatInt(size, 10) {
+		return fmt.Errorf("Content-Range '%s' does not match the snapshot size %d", contentRange, size)
+	}
+	return nil
+}
+
+// UploadSnapshot uploads a local snapshot archive to the stack, on behalf of the environment in us. The checksum
+// and size are calculated from the file, without changing us. The Dashboard processes the upload asynchronously, use
+// WaitForSnapshotTransfer to wait for the snapshot.
+func (a *Client) UploadSnapshot(sID string, path string, us *UploadSnapshot) (*SnapshotTransfer, error) {
+	if us.Environment == "" {
+		return nil, errors.New("environment is required")
+	}
+	if err := validateSnapshotMode(us.Mode); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	query := *us
+	query.Checksum = hex.EncodeToString(h.Sum(nil))
+	query.Size = size
+
+	url, err := addQuery(fmt.Sprintf("naut/project/%s/snapshots/upload", sID), &query)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	resp, err := a.stream("POST", url, f, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed uploading snapshot: '%s'", err)
+	}
+	defer resp.Body.Close()
+
+	return responseToSnapshotTransfer(resp.Body)
+}
+
+func responseToSnapshotTransfer(r io.Reader) (*SnapshotTransfer, error) {
+	t := new(SnapshotTransfer)
+	err := jsonapi.UnmarshalPayload(r, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling snapshot transfer: '%s'", err)
+	}
+
+	t.Mode = snapshotModes[t.OriginalMode]
+	t.State = transferStates[t.OriginalState]
+	postProcessSnapshot(t.Snapshot)
+
+	return t, nil
+}
+
+func postProcessSnapshot(s *Snapshot) {
+	if s == nil {
+		return
+	}
+	s.Mode = snapshotModes[s.OriginalMode]
+	postProcessEnvironment(s.Environment)
+}
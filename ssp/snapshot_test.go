@@ -0,0 +1,355 @@
+package ssp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var snapshotData = []byte(strings.Repeat("snapshot archive contents\n", 100))
+
+func snapshotChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// serveSnapshot serves data honouring "bytes=N-" ranges, and records the ranges requested. Ranges starting at
+// the end of the data are not satisfiable.
+func serveSnapshot(data []byte, ranges *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		*ranges = append(*ranges, rng)
+		w.Header().Set("Content-Type", "application/octet-stream")
+
+		var offset int
+		if _, err := fmt.Sscanf(rng, "bytes=%d-", &offset); err == nil {
+			if offset >= len(data) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(data)-1, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[offset:])
+			return
+		}
+		w.Write(data)
+	}
+}
+
+func snapshotRoutes(checksum string, ranges *[]string) map[string]mockRoute {
+	snapshot := &Snapshot{ID: "7", OriginalMode: "db", Size: int64(len(snapshotData)), Checksum: checksum}
+	return map[string]mockRoute{
+		"GET /naut/project/one/snapshots/7":          {http.StatusOK, snapshot},
+		"GET /naut/project/one/snapshots/7/download": {http.StatusOK, serveSnapshot(snapshotData, ranges)},
+	}
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	polls := 0
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/one/snapshots": {http.StatusCreated, &SnapshotTransfer{ID: "3", OriginalState: "Queued", OriginalMode: "all"}},
+		"GET /naut/project/one/snapshots/transfer/3": {http.StatusOK, func() interface{} {
+			polls++
+			if polls < 2 {
+				return &SnapshotTransfer{ID: "3", OriginalState: "Started"}
+			}
+			return &SnapshotTransfer{ID: "3", OriginalState: "Finished", Snapshot: &Snapshot{ID: "7", OriginalMode: "all"}}
+		}},
+	})
+	defer ts.Close()
+
+	transfer, err := api.CreateSnapshot("one", &CreateSnapshot{Environment: "prod", Mode: SnapshotAll})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if transfer.State != TransferQueued || transfer.Mode != SnapshotAll {
+		t.Errorf("Unexpected transfer %+v", transfer)
+	}
+	if body := string(m.requestsTo("POST", "/naut/project/one/snapshots")[0].body); body != `{"environment":"prod","mode":"all"}` {
+		t.Errorf("Unexpected request %s", body)
+	}
+
+	transfer, err = api.WaitForSnapshotTransfer(context.Background(), "one", "3", time.Millisecond)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if transfer.State != TransferFinished || transfer.Snapshot.ID != "7" || transfer.Snapshot.Mode != SnapshotAll {
+		t.Errorf("Unexpected finished transfer %+v", transfer)
+	}
+
+	if _, err := api.CreateSnapshot("one", &CreateSnapshot{Environment: "prod", Mode: "everything"}); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestWaitForSnapshotTransferFailed(t *testing.T) {
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/snapshots/transfer/3": {http.StatusOK, &SnapshotTransfer{ID: "3", OriginalState: "Failed", Message: "disk full"}},
+	})
+	defer ts.Close()
+
+	transfer, err := api.WaitForSnapshotTransfer(context.Background(), "one", "3", time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected failure, got %v", err)
+	}
+	if transfer == nil || transfer.State != TransferFailed {
+		t.Error("Expected the failed transfer")
+	}
+}
+
+func TestListAndDeleteSnapshots(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/snapshots": {http.StatusOK, []*Snapshot{
+			{ID: "7", OriginalMode: "db", Environment: &Environment{ID: "prod", OriginalUsage: "Production"}},
+			{ID: "8", OriginalMode: "assets"},
+		}},
+		"DELETE /naut/project/one/snapshots/7": {http.StatusNoContent, nil},
+	})
+	defer ts.Close()
+
+	snapshots, err := api.ListSnapshots("one")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Mode != SnapshotDatabase || snapshots[1].Mode != SnapshotAssets {
+		t.Errorf("Snapshots parsed incorrectly")
+	}
+	if snapshots[0].Environment.Usage != UsageProduction {
+		t.Error("Snapshot environment was not post-processed")
+	}
+
+	if err := api.DeleteSnapshot("one", "7"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(m.requestsTo("DELETE", "/naut/project/one/snapshots/7")) != 1 {
+		t.Error("Expected snapshot to be deleted")
+	}
+}
+
+func TestDownloadSnapshot(t *testing.T) {
+	var ranges []string
+	api, ts, _ := newMockRouter(snapshotRoutes(snapshotChecksum(snapshotData), &ranges))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.sspak")
+
+	// Pretend an earlier download was interrupted.
+	ioutil.WriteFile(path+".part", snapshotData[:1000], 0644)
+
+	snapshot, err := api.DownloadSnapshot("one", "7", path)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if snapshot.Mode != SnapshotDatabase {
+		t.Error("Expected snapshot metadata")
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=1000-" {
+		t.Errorf("Expected download to resume from 1000, got %v", ranges)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if string(data) != string(snapshotData) {
+		t.Error("Downloaded file does not match the snapshot")
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Error("Partial file should be moved into place")
+	}
+}
+
+func TestDownloadSnapshotChecksumMismatch(t *testing.T) {
+	var ranges []string
+	api, ts, _ := newMockRouter(snapshotRoutes(snapshotChecksum([]byte("something else")), &ranges))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.sspak")
+
+	if _, err := api.DownloadSnapshot("one", "7", path); err != ErrChecksumMismatch {
+		t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if ranges[0] != "" {
+		t.Errorf("Expected a full download, got range %s", ranges[0])
+	}
+	for _, p := range []string{path, path + ".part"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist", p)
+		}
+	}
+}
+
+func TestDownloadSnapshotCompletePart(t *testing.T) {
+	var ranges []string
+	routes := snapshotRoutes("", &ranges)
+	routes["GET /naut/project/one/snapshots/7"] = mockRoute{http.StatusOK, &Snapshot{ID: "7", Checksum: snapshotChecksum(snapshotData)}}
+	api, ts, _ := newMockRouter(routes)
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.sspak")
+	ioutil.WriteFile(path+".part", snapshotData, 0644)
+
+	if _, err := api.DownloadSnapshot("one", "7", path); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(ranges) != 0 {
+		t.Errorf("Complete download should not be requested again, got %v", ranges)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != string(snapshotData) {
+		t.Error("Downloaded file does not match the snapshot")
+	}
+}
+
+func TestDownloadSnapshotCorruptPart(t *testing.T) {
+	var ranges []string
+	api, ts, _ := newMockRouter(snapshotRoutes(snapshotChecksum(snapshotData), &ranges))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.sspak")
+	ioutil.WriteFile(path+".part", make([]byte, len(snapshotData)), 0644)
+
+	if _, err := api.DownloadSnapshot("one", "7", path); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "" {
+		t.Errorf("Expected a full download, got %v", ranges)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != string(snapshotData) {
+		t.Error("Downloaded file does not match the snapshot")
+	}
+}
+
+func TestDownloadSnapshotContentRange(t *testing.T) {
+	routes := snapshotRoutes(snapshotChecksum(snapshotData), nil)
+	routes["GET /naut/project/one/snapshots/7/download"] = mockRoute{http.StatusOK, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(snapshotData)-1, len(snapshotData)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(snapshotData)
+	})}
+	api, ts, _ := newMockRouter(routes)
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.sspak")
+	ioutil.WriteFile(path+".part", snapshotData[:1000], 0644)
+
+	_, err := api.DownloadSnapshot("one", "7", path)
+	if err == nil || !strings.Contains(err.Error(), "does not start at 1000") {
+		t.Errorf("Expected Content-Range error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Mismatched range should not be moved into place")
+	}
+}
+
+func TestDownloadSnapshotNoChecksum(t *testing.T) {
+	var ranges []string
+	api, ts, _ := newMockRouter(snapshotRoutes("", &ranges))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prod.sspak")
+
+	if _, err := api.DownloadSnapshot("one", "7", path); err != ErrNoChecksum {
+		t.Fatalf("Expected ErrNoChecksum, got %v", err)
+	}
+	if len(ranges) != 0 {
+		t.Error("Unverifiable snapshot should not be downloaded")
+	}
+}
+
+func TestWaitForSnapshotTransferDefaultInterval(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/snapshots/transfer/3": {http.StatusOK, &SnapshotTransfer{ID: "3", OriginalState: "Started"}},
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := api.WaitForSnapshotTransfer(ctx, "one", "3", 0); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if polls := len(m.requestsTo("GET", "/naut/project/one/snapshots/transfer/3")); polls != 1 {
+		t.Errorf("Zero interval should not busy-loop, got %d polls", polls)
+	}
+}
+
+func TestUploadAndRestoreSnapshot(t *testing.T) {
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/one/snapshots/upload":    {http.StatusCreated, &SnapshotTransfer{ID: "4", OriginalState: "Queued"}},
+		"POST /naut/project/one/snapshots/7/restore": {http.StatusCreated, &SnapshotTransfer{ID: "5", OriginalState: "Queued", OriginalMode: "db"}},
+	})
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "local.sspak")
+	ioutil.WriteFile(path, snapshotData, 0644)
+
+	transfer, err := api.UploadSnapshot("one", path, &UploadSnapshot{Environment: "uat", Mode: SnapshotAll})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if transfer.ID != "4" {
+		t.Errorf("Unexpected transfer %+v", transfer)
+	}
+
+	upload := m.requestsTo("POST", "/naut/project/one/snapshots/upload")[0]
+	if string(upload.body) != string(snapshotData) {
+		t.Error("Uploaded body does not match the file")
+	}
+	if upload.query.Get("checksum") != snapshotChecksum(snapshotData) || upload.query.Get("size") != fmt.Sprint(len(snapshotData)) {
+		t.Errorf("Unexpected upload query %v", upload.query)
+	}
+
+	transfer, err = api.RestoreSnapshot("one", "7", &RestoreSnapshot{Environment: "uat", Mode: SnapshotDatabase})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if transfer.Mode != SnapshotDatabase {
+		t.Errorf("Unexpected transfer %+v", transfer)
+	}
+	if body := string(m.requestsTo("POST", "/naut/project/one/snapshots/7/restore")[0].body); body != `{"environment":"uat","mode":"db"}` {
+		t.Errorf("Unexpected request %s", body)
+	}
+
+	if _, err := api.RestoreSnapshot("one", "7", &RestoreSnapshot{}); err == nil {
+		t.Error("Expected error without environment")
+	}
+}
+
+func TestUploadSnapshotKeepsRequest(t *testing.T) {
+	api, ts, _ := newMockRouter(map[string]mockRoute{
+		"POST /naut/project/one/snapshots/upload": {http.StatusCreated, &SnapshotTransfer{ID: "4", OriginalState: "Queued"}},
+	})
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "snapshot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "local.sspak")
+	ioutil.WriteFile(path, snapshotData, 0644)
+
+	us := &UploadSnapshot{Environment: "uat", Mode: SnapshotAll}
+	if _, err := api.UploadSnapshot("one", path, us); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if *us != (UploadSnapshot{Environment: "uat", Mode: SnapshotAll}) {
+		t.Errorf("Upload request should not change, got %+v", us)
+	}
+}
@@ -7,6 +7,20 @@ import (
 	"time"
 )
 
+// Relationships that can be included in the response of GetStack, ListEnvironments and GetEnvironment.
+const (
+	IncludeEnvironments    = "environments"
+	IncludeBaseStack       = "base_stack"
+	IncludeStack           = "stack"
+	IncludeBaseEnvironment = "base_environment"
+	// IncludeBaseEnvironmentStack includes the base environment together with its stack.
+	IncludeBaseEnvironmentStack = "base_environment.stack"
+)
+
+type includeQuery struct {
+	Include []string `url:"include,omitempty,comma"`
+}
+
 type Stack struct {
 	ID           string         `jsonapi:"primary,stacks"`
 	Name         string         `jsonapi:"attr,name"`
@@ -31,7 +45,33 @@ func (a *Client) ListStacks() ([]*Stack, error) {
 	stacks := make([]*Stack, len(items))
 	for i, item := range items {
 		stacks[i] = item.(*Stack)
+		for _, env := range stacks[i].Environments {
+			postProcessEnvironment(env)
+		}
 	}
 
 	return stacks, nil
 }
+
+// GetStack returns a single stack. Related resources, such as IncludeEnvironments, can be fetched in the same
+// request.
+func (a *Client) GetStack(sID string, include ...string) (*Stack, error) {
+	url := fmt.Sprintf("naut/project/%s", sID)
+	r, err := a.getQuery(url, &includeQuery{Include: include})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	stack := new(Stack)
+	err = jsonapi.UnmarshalPayload(r, stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling stack: '%s'", err)
+	}
+
+	for _, env := range stack.Environments {
+		postProcessEnvironment(env)
+	}
+
+	return stack, nil
+}
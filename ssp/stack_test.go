@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestListStacks(t *testing.T) {
@@ -23,3 +24,36 @@ func TestListStacks(t *testing.T) {
 		t.Error("Data returned is not matching the data sent")
 	}
 }
+
+func TestGetStack(t *testing.T) {
+	in := &Stack{
+		ID:    "one",
+		Title: "One",
+		Environments: []*Environment{
+			{ID: "prod", OriginalUsage: "Production", OriginalMaintenanceDay: "Monday", OriginalCurrentManifestSha: "1.2.3"},
+			{ID: "uat", OriginalUsage: "UAT"},
+		},
+	}
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one": {http.StatusOK, in},
+	})
+	defer ts.Close()
+
+	out, err := api.GetStack("one", IncludeEnvironments)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if out.Title != "One" || len(out.Environments) != 2 {
+		t.Fatalf("Stack parsed incorrectly: %+v", out)
+	}
+
+	prod := out.Environments[0]
+	if prod.Usage != UsageProduction || prod.MaintenanceDay != time.Monday || prod.CurrentManifestSha.String() != "1.2.3" {
+		t.Error("Included environment was not post-processed")
+	}
+
+	reqs := m.requestsTo("GET", "/naut/project/one")
+	if len(reqs) != 1 || reqs[0].query.Get("include") != "environments" {
+		t.Errorf("Expected include=environments, got %v", reqs)
+	}
+}
@@ -0,0 +1,212 @@
+package ssp
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type DeploymentEventType string
+
+const (
+	DeploymentEventCreated      = "created"
+	DeploymentEventStateChanged = "state_changed"
+	DeploymentEventCompleted    = "completed"
+	DeploymentEventFailed       = "failed"
+)
+
+// DeploymentEvent describes a change to a deployment observed by WatchDeployments. PreviousState is empty for
+// deployments that have not been seen before.
+type DeploymentEvent struct {
+	Type          DeploymentEventType
+	Environment   EnvironmentRef
+	Deployment    *Deployment
+	PreviousState State
+}
+
+// WatchCursor records how far a watch has progressed for each environment, keyed by EnvironmentRef.String().
+// It can be serialised and passed to a later watch to resume without repeating events.
+type WatchCursor struct {
+	mu           sync.Mutex
+	Environments map[string]*EnvironmentCursor `json:"environments"`
+}
+
+// EnvironmentCursor is the watch progress of a single environment: the latest deployment edit seen, and the
+// last state seen of each deployment. Deployments in a final state are forgotten once they were last edited before
+// LastEdited.
+type EnvironmentCursor struct {
+	LastEdited time.Time     `json:"last_edited"`
+	States     map[int]State `json:"states"`
+}
+
+// WatchDeployments configures a deployment watch. MinInterval is the polling interval while there is activity.
+// Each idle poll doubles the interval, up to MaxInterval.
+//
+// Environments without an entry in Cursor are watched from the time the watch starts. OnError, if set,
+// receives polling errors, which are otherwise ignored and retried.
+type WatchDeployments struct {
+	Environments []EnvironmentRef
+	MinInterval  time.Duration
+	MaxInterval  time.Duration
+	Cursor       *WatchCursor
+	OnError      func(env EnvironmentRef, err error)
+}
+
+// WatchDeployments polls the deployments of the environments and emits events on the returned channel until the
+// context is cancelled, at which point the channel is closed. The cursor is safe to read once the channel is closed.
+func (a *Client) WatchDeployments(ctx context.Context, wd *WatchDeployments) <-chan *DeploymentEvent {
+	minInterval, maxInterval := wd.MinInterval, wd.MaxInterval
+	if minInterval <= 0 {
+		minInterval = 5 * time.Second
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval * 12
+	}
+
+	if wd.Cursor == nil {
+		wd.Cursor = &WatchCursor{}
+	}
+	cursor := wd.Cursor
+	cursor.mu.Lock()
+	if cursor.Environments == nil {
+		cursor.Environments = map[string]*EnvironmentCursor{}
+	}
+	started := time.Now()
+	for _, env := range wd.Environments {
+		if _, ok := cursor.Environments[env.String()]; !ok {
+			cursor.Environments[env.String()] = &EnvironmentCursor{LastEdited: started}
+		}
+	}
+	cursor.mu.Unlock()
+
+	events := make(chan *DeploymentEvent)
+	go func() {
+		defer close(events)
+
+		interval := minInterval
+		for {
+			emitted := 0
+			for _, env := range wd.Environments {
+				deployments, err := a.ListDeployments(env.Stack, env.Environment, &DeploymentFilter{
+					LastEditedFrom: cursor.get(env).LastEdited,
+				})
+				if err != nil {
+					if wd.OnError != nil {
+						wd.OnError(env, err)
+					}
+					continue
+				}
+
+				pending := cursor.pending(env, deployments)
+				for i, e := range pending {
+					select {
+					case events <- e:
+						emitted++
+					case <-ctx.Done():
+						return
+					}
+					// Only move the cursor once all events of a deployment went out, so a resumed watch repeats
+					// none and misses none.
+					if i == len(pending)-1 || pending[i+1].Deployment != e.Deployment {
+						cursor.commit(env, e.Deployment)
+					}
+				}
+				cursor.prune(env, deployments)
+			}
+
+			if emitted > 0 {
+				interval = minInterval
+			} else if interval *= 2; interval > maxInterval {
+				interval = maxInterval
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+
+	return events
+}
+
+func (c *WatchCursor) get(env EnvironmentRef) EnvironmentCursor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *c.Environments[env.String()]
+}
+
+// pending returns the events the deployments represent, oldest edit first. Deployments already seen in the
+// same state are skipped.
+func (c *WatchCursor) pending(env EnvironmentRef, deployments []*Deployment) []*DeploymentEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ec := c.Environments[env.String()]
+
+	sort.Slice(deployments, func(i, j int) bool {
+		if !deployments[i].DateUpdated.Equal(deployments[j].DateUpdated) {
+			return deployments[i].DateUpdated.Before(deployments[j].DateUpdated)
+		}
+		return deployments[i].ID < deployments[j].ID
+	})
+
+	events := []*DeploymentEvent{}
+	since := ec.LastEdited
+	for _, d := range deployments {
+		previous, seen := ec.States[d.ID]
+		switch {
+		case !seen && !d.DateCreated.Before(since):
+			events = append(events, &DeploymentEvent{Type: DeploymentEventCreated, Environment: env, Deployment: d})
+		case !seen || previous != d.State:
+			events = append(events, &DeploymentEvent{Type: DeploymentEventStateChanged, Environment: env, Deployment: d, PreviousState: previous})
+		default:
+			continue
+		}
+
+		switch d.State {
+		case StateCompleted:
+			events = append(events, &DeploymentEvent{Type: DeploymentEventCompleted, Environment: env, Deployment: d, PreviousState: previous})
+		case StateFailed:
+			events = append(events, &DeploymentEvent{Type: DeploymentEventFailed, Environment: env, Deployment: d, PreviousState: previous})
+		}
+	}
+
+	return events
+}
+
+// commit records the deployment as seen in its current state.
+func (c *WatchCursor) commit(env EnvironmentRef, d *Deployment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ec := c.Environments[env.String()]
+	if ec.States == nil {
+		ec.States = map[int]State{}
+	}
+	ec.States[d.ID] = d.State
+	if d.DateUpdated.After(ec.LastEdited) {
+		ec.LastEdited = d.DateUpdated
+	}
+}
+
+// prune forgets deployments in a final state that were last edited before the cursor, and so are no longer listed.
+// They cannot change any more, and remembering them would grow the cursor forever.
+func (c *WatchCursor) prune(env EnvironmentRef, listed []*Deployment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := map[int]bool{}
+	for _, d := range listed {
+		current[d.ID] = true
+	}
+
+	ec := c.Environments[env.String()]
+	for id, state := range ec.States {
+		if state.Final() && !current[id] {
+			delete(ec.States, id)
+		}
+	}
+}
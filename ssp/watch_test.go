@@ -0,0 +1,100 @@
+package ssp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchDeployments(t *testing.T) {
+	since := time.Now().Add(-time.Hour).Truncate(time.Second)
+	mu := &sync.Mutex{}
+	polls := 0
+	responses := [][]*Deployment{
+		{
+			{ID: 1, OriginalState: "Deploying", DateCreated: since.Add(-time.Hour), DateUpdated: since.Add(time.Minute)},
+		},
+		{
+			{ID: 1, OriginalState: "Deploying", DateCreated: since.Add(-time.Hour), DateUpdated: since.Add(time.Minute)},
+		},
+		{
+			{ID: 1, OriginalState: "Completed", DateCreated: since.Add(-time.Hour), DateUpdated: since.Add(2 * time.Minute)},
+			{ID: 2, OriginalState: "New", DateCreated: since.Add(3 * time.Minute), DateUpdated: since.Add(3 * time.Minute)},
+		},
+	}
+	api, ts, m := newMockRouter(map[string]mockRoute{
+		"GET /naut/project/one/environment/prod/deploys": {http.StatusOK, func() interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			r := responses[len(responses)-1]
+			if polls < len(responses) {
+				r = responses[polls]
+			}
+			polls++
+			return r
+		}},
+	})
+	defer ts.Close()
+
+	env := EnvironmentRef{Stack: "one", Environment: "prod"}
+	cursor := &WatchCursor{Environments: map[string]*EnvironmentCursor{
+		env.String(): {LastEdited: since, States: map[int]State{1: StateQueued}},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := api.WatchDeployments(ctx, &WatchDeployments{
+		Environments: []EnvironmentRef{env},
+		MinInterval:  time.Millisecond,
+		MaxInterval:  2 * time.Millisecond,
+		Cursor:       cursor,
+	})
+
+	expected := []struct {
+		typ DeploymentEventType
+		id  int
+	}{
+		{DeploymentEventStateChanged, 1},
+		{DeploymentEventStateChanged, 1},
+		{DeploymentEventCompleted, 1},
+		{DeploymentEventCreated, 2},
+	}
+	for i, exp := range expected {
+		select {
+		case e := <-events:
+			if e.Type != exp.typ || e.Deployment.ID != exp.id {
+				t.Errorf("Event %d: expected %s of %d, got %s of %d", i, exp.typ, exp.id, e.Type, e.Deployment.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event %d", i)
+		}
+	}
+
+	cancel()
+	for e := range events {
+		t.Errorf("Unexpected event %s of %d", e.Type, e.Deployment.ID)
+	}
+
+	ec := cursor.Environments[env.String()]
+	if !ec.LastEdited.Equal(since.Add(3*time.Minute)) || ec.States[1] != StateCompleted || ec.States[2] != StateNew {
+		t.Errorf("Cursor not advanced: %+v", ec)
+	}
+	reqs := m.requestsTo("GET", "/naut/project/one/environment/prod/deploys")
+	if reqs[0].query.Get("lastedited_from_unix") == "" {
+		t.Error("Deployments not filtered by last edit")
+	}
+}
+
+func TestWatchCursorPrune(t *testing.T) {
+	env := EnvironmentRef{Stack: "one", Environment: "prod"}
+	cursor := &WatchCursor{Environments: map[string]*EnvironmentCursor{
+		env.String(): {States: map[int]State{1: StateCompleted, 2: StateDeploying, 3: StateFailed}},
+	}}
+
+	cursor.prune(env, []*Deployment{{ID: 3, State: StateFailed}})
+
+	states := cursor.Environments[env.String()].States
+	if len(states) != 2 || states[2] != StateDeploying || states[3] != StateFailed {
+		t.Errorf("Expected only final deployments no longer listed to be pruned, got %v", states)
+	}
+}
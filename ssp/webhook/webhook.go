@@ -0,0 +1,205 @@
+// Package webhook receives deployment notifications pushed by the Platform Dashboard.
+//
+// The Dashboard signs each notification with a secret shared with the receiver. The signature is sent in the
+// X-Dashboard-Signature header as "sha256=" followed by the hex-encoded HMAC-SHA256 of the X-Dashboard-Timestamp
+// header value, a dot, and the request body. The body is a JSON object:
+//
+//	{"event": "deployment.completed", "deployment": {"data": {...}, "included": [...]}}
+//
+// where deployment is a JSON API document of the deployment, including its environment and stack.
+//
+// Quick start
+//
+//	h, err := webhook.NewHandler(os.Getenv("WEBHOOK_SECRET"), func(e *webhook.Event) error {
+//		if e.Environment != nil {
+//			fmt.Printf("%s: deployment %d on %s\n", e.Type, e.Deployment.ID, e.Environment.Name)
+//		}
+//		return nil
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	http.Handle("/dashboard", h)
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+const (
+	SignatureHeader = "X-Dashboard-Signature"
+	TimestampHeader = "X-Dashboard-Timestamp"
+)
+
+type EventType string
+
+const (
+	EventDeploymentCreated   = "deployment.created"
+	EventDeploymentApproved  = "deployment.approved"
+	EventDeploymentRejected  = "deployment.rejected"
+	EventDeploymentStarted   = "deployment.started"
+	EventDeploymentCompleted = "deployment.completed"
+	EventDeploymentFailed    = "deployment.failed"
+)
+
+// DefaultTolerance is the maximum age of a notification accepted by a Handler created with NewHandler.
+const DefaultTolerance = 5 * time.Minute
+
+const maxBodySize = 1 << 20
+
+// ErrEmptySecret is returned when creating a Handler without a secret.
+var ErrEmptySecret = errors.New("webhook secret is empty")
+
+// Event is a verified and decoded Dashboard notification. Environment and Stack are taken from the deployment
+// relations, and are nil if the Dashboard did not include them.
+type Event struct {
+	Type        EventType
+	Timestamp   time.Time
+	Deployment  *ssp.Deployment
+	Environment *ssp.Environment
+	Stack       *ssp.Stack
+}
+
+// Handler is an http.Handler verifying and decoding Dashboard notifications before passing them to OnEvent.
+//
+// Notifications with a timestamp further than Tolerance from the current time are rejected as replays, and a zero
+// Tolerance means DefaultTolerance. A Handler without a Secret rejects all notifications with a 500 response, as
+// anyone could sign them. An error returned from OnEvent results in a 500 response, so the Dashboard can retry the notification.
+type Handler struct {
+	Secret    []byte
+	Tolerance time.Duration
+	OnEvent   func(e *Event) error
+}
+
+// NewHandler creates a Handler with the DefaultTolerance. The secret must not be empty.
+func NewHandler(secret string, onEvent func(e *Event) error) (*Handler, error) {
+	if secret == "" {
+		return nil, ErrEmptySecret
+	}
+
+	return &Handler{
+		Secret:    []byte(secret),
+		Tolerance: DefaultTolerance,
+		OnEvent:   onEvent,
+	}, nil
+}
+
+type payload struct {
+	Event      EventType       `json:"event"`
+	Deployment json.RawMessage `json:"deployment"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(h.Secret) == 0 {
+		http.Error(w, ErrEmptySecret.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed reading body", http.StatusBadRequest)
+		return
+	}
+
+	ts, err := h.verify(r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	e, err := decode(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e.Timestamp = ts
+
+	if h.OnEvent != nil {
+		if err := h.OnEvent(e); err != nil {
+			http.Error(w, "failed handling event", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) verify(header http.Header, body []byte) (time.Time, error) {
+	unix, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("missing or invalid %s", TimestampHeader)
+	}
+	ts := time.Unix(unix, 0)
+
+	age := time.Since(ts)
+	if age < 0 {
+		age = -age
+	}
+	tolerance := h.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	if age > tolerance {
+		return time.Time{}, fmt.Errorf("timestamp outside of tolerance")
+	}
+
+	sig := header.Get(SignatureHeader)
+	if !strings.HasPrefix(sig, "sha256=") {
+		return time.Time{}, fmt.Errorf("missing or invalid %s", SignatureHeader)
+	}
+	if !hmac.Equal([]byte(sig), []byte(Sign(h.Secret, ts, body))) {
+		return time.Time{}, fmt.Errorf("signature mismatch")
+	}
+
+	return ts, nil
+}
+
+func decode(body []byte) (*Event, error) {
+	p := &payload{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, fmt.Errorf("failed decoding payload: '%s'", err)
+	}
+	if p.Event == "" {
+		return nil, fmt.Errorf("missing event type")
+	}
+	if len(p.Deployment) == 0 {
+		return nil, fmt.Errorf("missing deployment")
+	}
+
+	d, err := ssp.DecodeDeployment(bytes.NewReader(p.Deployment))
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding deployment: '%s'", err)
+	}
+
+	return &Event{
+		Type:        p.Event,
+		Deployment:  d,
+		Environment: d.Environment,
+		Stack:       d.Stack,
+	}, nil
+}
+
+// Sign computes the signature header value of a notification body sent at the given time.
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", timestamp.Unix())
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
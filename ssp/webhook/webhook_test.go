@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/silverstripeltd/ssp-sdk-go/ssp"
+)
+
+func notification(t *testing.T, event EventType) []byte {
+	d := &ssp.Deployment{
+		ID:            12,
+		OriginalState: "Completed",
+		Environment: &ssp.Environment{
+			ID:                          "prod",
+			Name:                        "Production",
+			OriginalUsage:               "Production",
+			OriginalMaintenanceDay:      "Tuesday",
+			OriginalMaintenanceTime:     "02:00:00",
+			OriginalMaintenanceDuration: "01:00:00",
+			OriginalMaintenanceTz:       "Pacific/Auckland",
+		},
+		Stack: &ssp.Stack{ID: "one", Name: "One"},
+	}
+	doc := &bytes.Buffer{}
+	if err := jsonapi.MarshalPayload(doc, d); err != nil {
+		t.Fatalf("%s", err)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"event":      event,
+		"deployment": json.RawMessage(doc.Bytes()),
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return body
+}
+
+func send(h *Handler, body []byte, ts time.Time, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/dashboard", bytes.NewReader(body))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign([]byte(secret), ts, body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func mustHandler(t *testing.T, secret string, onEvent func(e *Event) error) *Handler {
+	h, err := NewHandler(secret, onEvent)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return h
+}
+
+func TestHandler(t *testing.T) {
+	var received *Event
+	h := mustHandler(t, "secret", func(e *Event) error {
+		received = e
+		return nil
+	})
+
+	rec := send(h, notification(t, EventDeploymentCompleted), time.Now(), "secret")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected HTTP 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if received == nil {
+		t.Fatal("Event not delivered")
+	}
+	if received.Type != EventDeploymentCompleted {
+		t.Errorf("Unexpected event type '%s'", received.Type)
+	}
+	if received.Deployment.ID != 12 || received.Deployment.State != ssp.StateCompleted {
+		t.Error("Deployment decoded incorrectly")
+	}
+	if received.Environment == nil || received.Environment.Name != "Production" {
+		t.Error("Environment decoded incorrectly")
+	}
+	if received.Environment.Usage != ssp.UsageProduction {
+		t.Errorf("Unexpected environment usage '%s'", received.Environment.Usage)
+	}
+	if received.Environment.MaintenanceUnspecified || received.Environment.MaintenanceWindow.Day != time.Tuesday {
+		t.Error("Environment maintenance window not post-processed")
+	}
+	if received.Stack == nil || received.Stack.Name != "One" {
+		t.Error("Stack decoded incorrectly")
+	}
+}
+
+func TestHandlerRejectsBadSignature(t *testing.T) {
+	h := mustHandler(t, "secret", func(e *Event) error {
+		t.Error("Event should not be delivered")
+		return nil
+	})
+
+	rec := send(h, notification(t, EventDeploymentCompleted), time.Now(), "wrong")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected HTTP 401, got %d", rec.Code)
+	}
+}
+
+func TestHandlerRejectsReplay(t *testing.T) {
+	h := mustHandler(t, "secret", func(e *Event) error {
+		t.Error("Event should not be delivered")
+		return nil
+	})
+
+	rec := send(h, notification(t, EventDeploymentCompleted), time.Now().Add(-time.Hour), "secret")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected HTTP 401, got %d", rec.Code)
+	}
+}
+
+func TestHandlerCallbackError(t *testing.T) {
+	h := mustHandler(t, "secret", func(e *Event) error {
+		return errors.New("boom")
+	})
+
+	rec := send(h, notification(t, EventDeploymentCompleted), time.Now(), "secret")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected HTTP 500, got %d", rec.Code)
+	}
+}
+
+func TestHandlerWithServer(t *testing.T) {
+	events := make(chan *Event, 1)
+	ts := httptest.NewServer(mustHandler(t, "secret", func(e *Event) error {
+		events <- e
+		return nil
+	}))
+	defer ts.Close()
+
+	body := notification(t, EventDeploymentStarted)
+	now := time.Now()
+	req, _ := http.NewRequest("POST", ts.URL, bytes.NewReader(body))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign([]byte("secret"), now, body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected HTTP 204, got %d", resp.StatusCode)
+	}
+
+	e := <-events
+	if e.Type != EventDeploymentStarted {
+		t.Errorf("Unexpected event type '%s'", e.Type)
+	}
+
+	resp, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected HTTP 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestNewHandlerEmptySecret(t *testing.T) {
+	_, err := NewHandler("", func(e *Event) error { return nil })
+	if err != ErrEmptySecret {
+		t.Errorf("Expected ErrEmptySecret, got %v", err)
+	}
+}
+
+func TestHandlerWithoutSecret(t *testing.T) {
+	h := &Handler{OnEvent: func(e *Event) error {
+		t.Error("Event should not be delivered")
+		return nil
+	}}
+
+	rec := send(h, notification(t, EventDeploymentCompleted), time.Now(), "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected HTTP 500, got %d", rec.Code)
+	}
+}
+
+func TestHandlerDefaultTolerance(t *testing.T) {
+	delivered := false
+	h := &Handler{Secret: []byte("secret"), OnEvent: func(e *Event) error {
+		delivered = true
+		return nil
+	}}
+
+	rec := send(h, notification(t, EventDeploymentCompleted), time.Now().Add(-time.Minute), "secret")
+	if rec.Code != http.StatusNoContent || !delivered {
+		t.Errorf("Expected HTTP 204, got %d", rec.Code)
+	}
+
+	rec = send(h, notification(t, EventDeploymentCompleted), time.Now().Add(-time.Hour), "secret")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected HTTP 401, got %d", rec.Code)
+	}
+}